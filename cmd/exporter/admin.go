@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// adminAPI exposes runtime control of scraping, authenticated with a bearer token
+type adminAPI struct {
+	token   string
+	targets *collector.Targets
+	// targetsFile receives persisted target changes ("" disables persistence)
+	targetsFile string
+}
+
+// adminTarget is a target as shown by the admin API, without its password
+type adminTarget struct {
+	URL         string            `json:"url"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Schema      string            `json:"schema,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	PasswordSet bool              `json:"password_set"`
+}
+
+// adminTargetUpdate retargets the target with URL, keeping unset fields
+type adminTargetUpdate struct {
+	URL      string  `json:"url"`
+	NewURL   string  `json:"new_url"`
+	Username *string `json:"username"`
+	Password *string `json:"password"`
+	// Persist writes the updated targets back to --targets-file
+	Persist bool `json:"persist"`
+}
+
+func (a *adminAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/pause", a.auth(a.pause))
+	mux.HandleFunc("/admin/resume", a.auth(a.resume))
+	mux.HandleFunc("/admin/targets", a.auth(a.handleTargets))
+}
+
+// auth rejects requests without the admin bearer token
+func (a *adminAPI) auth(next http.HandlerFunc) http.HandlerFunc {
+	expected := []byte("Bearer " + a.token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *adminAPI) pause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.targets.Pause()
+	a.writeState(w)
+}
+
+func (a *adminAPI) resume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.targets.Resume()
+	a.writeState(w)
+}
+
+func (a *adminAPI) handleTargets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.writeState(w)
+	case http.MethodPut:
+		a.updateTarget(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *adminAPI) updateTarget(w http.ResponseWriter, r *http.Request) {
+	var update adminTargetUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if update.Persist && a.targetsFile == "" {
+		http.Error(w, "persisting requires --targets-file", http.StatusBadRequest)
+		return
+	}
+
+	var target collector.Target
+	found := false
+	for _, t := range a.targets.List() {
+		if t.URL == update.URL {
+			target, found = t, true
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("unknown target %q", update.URL), http.StatusNotFound)
+		return
+	}
+	if update.NewURL != "" {
+		var err error
+		if target, err = target.WithURL(update.NewURL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if update.Username != nil {
+		target.Username = *update.Username
+	}
+	if update.Password != nil {
+		target.Password = *update.Password
+	}
+	if err := collector.ValidateTargets([]collector.Target{target}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.targets.Replace(update.URL, target); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.WithFields(log.Fields{"from": update.URL, "to": target.URL}).Info("Target updated through admin API")
+
+	if update.Persist {
+		if err := persistTargets(a.targetsFile, a.targets.List()); err != nil {
+			log.WithError(err).Error("Failed to persist targets")
+			http.Error(w, "target updated but not persisted: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	a.writeState(w)
+}
+
+func (a *adminAPI) writeState(w http.ResponseWriter) {
+	list := a.targets.List()
+	state := struct {
+		Paused  bool          `json:"paused"`
+		Targets []adminTarget `json:"targets"`
+	}{Paused: a.targets.Paused(), Targets: make([]adminTarget, 0, len(list))}
+	for _, t := range list {
+		state.Targets = append(state.Targets, adminTarget{
+			URL:         t.URL,
+			Labels:      t.Labels,
+			Schema:      t.Schema,
+			Username:    t.Username,
+			PasswordSet: t.Password != "",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// persistTargets atomically rewrites the targets of the targets file, keeping
+// its other blocks. Targets are written as they were before rendering the
+// file's templates, which are kept. It may hold credentials, so it is only
+// readable by the owner.
+func persistTargets(file string, targets []collector.Target) error {
+	content := make(map[string]interface{})
+	if existing, err := os.ReadFile(file); err == nil {
+		if err := json.Unmarshal(existing, &content); err != nil {
+			return err
+		}
+	}
+	unrendered := make([]collector.Target, 0, len(targets))
+	for _, target := range targets {
+		unrendered = append(unrendered, target.Unrendered())
+	}
+	content["targets"] = unrendered
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// alertsConfig holds the thresholds of the generated rules
+type alertsConfig struct {
+	Selector           string
+	Indent             string
+	DownFor            string
+	StatusFor          string
+	HeapRatio          float64
+	HeapFor            string
+	EventLoopSeconds   float64
+	EventLoopFor       string
+	ErrorRatio         float64
+	ErrorFor           string
+	PrometheusRule     bool
+	PrometheusRuleName string
+}
+
+var alertsTemplate = template.Must(template.New("alerts").Parse(`{{- if .PrometheusRule -}}
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: {{ .PrometheusRuleName }}
+spec:
+{{ end -}}
+{{ .Indent }}groups:
+{{ .Indent }}  - name: kibana.recording
+{{ .Indent }}    rules:
+{{ .Indent }}      - record: kibana:heap_usage:ratio
+{{ .Indent }}        expr: kibana_heap_used_bytes{{ .Selector }} / kibana_heap_size_limit_bytes{{ .Selector }}
+{{ .Indent }}      - record: kibana:requests:rate5m
+{{ .Indent }}        expr: avg_over_time(kibana_requests_per_second{{ .Selector }}[5m])
+{{ .Indent }}      - record: kibana:requests_5xx:ratio_rate5m
+{{ .Indent }}        expr: sum without (status) (avg_over_time(kibana_requests_total{{ .StatusSelector "5.." }}[5m])) / sum without (status) (avg_over_time(kibana_requests_total{{ .StatusSelector "[1-5].." }}[5m]))
+{{ .Indent }}  - name: kibana.alerts
+{{ .Indent }}    rules:
+{{ .Indent }}      - alert: KibanaDown
+{{ .Indent }}        expr: kibana_up{{ .Selector }} == 0
+{{ .Indent }}        for: {{ .DownFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: critical
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} is down"
+{{ .Indent }}          description: "The exporter has not been able to scrape Kibana for {{ .DownFor }}."
+{{ .Indent }}      - alert: KibanaStatusRed
+{{ .Indent }}        expr: kibana_status_overall{{ .Selector }} == 0
+{{ .Indent }}        for: {{ .StatusFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: critical
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} status is red"
+{{ .Indent }}          description: "Kibana has reported an unavailable overall status for {{ .StatusFor }}."
+{{ .Indent }}      - alert: KibanaStatusYellow
+{{ .Indent }}        expr: kibana_status_overall{{ .Selector }} == 0.5
+{{ .Indent }}        for: {{ .StatusFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: warning
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} status is yellow"
+{{ .Indent }}          description: "Kibana has reported a degraded overall status for {{ .StatusFor }}."
+{{ .Indent }}      - alert: KibanaHeapNearLimit
+{{ .Indent }}        expr: kibana:heap_usage:ratio > {{ .HeapRatio }}
+{{ .Indent }}        for: {{ .HeapFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: warning
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} heap is near its limit"
+{{ .Indent }}          description: "Heap usage is {{ "{{ $value | humanizePercentage }}" }} of the limit."
+{{ .Indent }}      - alert: KibanaEventLoopDelayHigh
+{{ .Indent }}        expr: kibana_event_loop_delay_seconds{{ .Selector }} > {{ .EventLoopSeconds }}
+{{ .Indent }}        for: {{ .EventLoopFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: warning
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} event loop delay is high"
+{{ .Indent }}          description: "Event loop delay is {{ "{{ $value | humanizeDuration }}" }}."
+{{ .Indent }}      - alert: KibanaHighErrorRate
+{{ .Indent }}        expr: kibana:requests_5xx:ratio_rate5m > {{ .ErrorRatio }}
+{{ .Indent }}        for: {{ .ErrorFor }}
+{{ .Indent }}        labels:
+{{ .Indent }}          severity: warning
+{{ .Indent }}        annotations:
+{{ .Indent }}          summary: "Kibana {{ "{{ $labels.instance }}" }} is returning 5xx responses"
+{{ .Indent }}          description: "{{ "{{ $value | humanizePercentage }}" }} of requests failed with a 5xx status."
+`))
+
+// StatusSelector returns the configured selector extended with a status regex matcher
+func (c alertsConfig) StatusSelector(pattern string) string {
+	matcher := fmt.Sprintf(`status=~"%s"`, pattern)
+	if c.Selector == "" {
+		return "{" + matcher + "}"
+	}
+	return strings.TrimSuffix(c.Selector, "}") + ", " + matcher + "}"
+}
+
+func runGenerateAlerts(args []string) int {
+	fs := flag.NewFlagSet("generate-alerts", flag.ContinueOnError)
+	selector := fs.String("selector", "", `Label matchers applied to every rule, e.g. 'cluster="prod"'`)
+	downFor := fs.Duration("down-for", 5*time.Minute, "How long Kibana must be unreachable before KibanaDown fires")
+	statusFor := fs.Duration("status-for", 10*time.Minute, "How long the overall status must be red/yellow before alerting")
+	heapRatio := fs.Float64("heap-threshold", 0.9, "Heap used / heap limit ratio that triggers KibanaHeapNearLimit")
+	heapFor := fs.Duration("heap-for", 5*time.Minute, "How long heap usage must stay above the threshold")
+	eventLoop := fs.Duration("event-loop-threshold", 500*time.Millisecond, "Event loop delay that triggers KibanaEventLoopDelayHigh")
+	eventLoopFor := fs.Duration("event-loop-for", 5*time.Minute, "How long event loop delay must stay above the threshold")
+	errorRatio := fs.Float64("error-rate-threshold", 0.05, "Fraction of 5xx responses that triggers KibanaHighErrorRate")
+	errorFor := fs.Duration("error-rate-for", 10*time.Minute, "How long the 5xx ratio must stay above the threshold")
+	format := fs.String("format", "prometheus", "Output format: prometheus (rule file) or prometheusrule (Prometheus Operator CRD)")
+	name := fs.String("name", "kibana-alerts", "metadata.name of the PrometheusRule")
+	output := fs.String("output", "-", "Output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	config := alertsConfig{
+		DownFor:            promDuration(*downFor),
+		StatusFor:          promDuration(*statusFor),
+		HeapRatio:          *heapRatio,
+		HeapFor:            promDuration(*heapFor),
+		EventLoopSeconds:   eventLoop.Seconds(),
+		EventLoopFor:       promDuration(*eventLoopFor),
+		ErrorRatio:         *errorRatio,
+		ErrorFor:           promDuration(*errorFor),
+		PrometheusRuleName: *name,
+	}
+	if *selector != "" {
+		config.Selector = "{" + *selector + "}"
+	}
+	switch *format {
+	case "prometheus":
+	case "prometheusrule":
+		config.PrometheusRule = true
+		config.Indent = "  "
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+
+	out, err := openOutput(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer out.Close()
+
+	if err := alertsTemplate.Execute(out, config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// promDuration formats d in Prometheus duration syntax, e.g. 1h30m
+func promDuration(d time.Duration) string {
+	s := d.String()
+	s = strings.Replace(s, "m0s", "m", 1)
+	s = strings.Replace(s, "h0m", "h", 1)
+	return s
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/pkg/kibana"
+)
+
+// parseAuthParams parses --auth-provider-param, a comma-separated list of
+// key=value settings for the auth provider
+func parseAuthParams(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	params := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected key=value", item)
+		}
+		params[key] = val
+	}
+	return params, nil
+}
+
+// credentialFlags are the Kibana credential flags of the exporter and of the
+// check and report subcommands, so that they authenticate the same way
+type credentialFlags struct {
+	username, usernameFile       *string
+	password, passwordFile       *string
+	apiKey, apiKeyFile           *string
+	bearerToken, bearerTokenFile *string
+}
+
+func registerCredentialFlags(fs *flag.FlagSet) credentialFlags {
+	return credentialFlags{
+		username:        fs.String("kibana-username", "", "Username for Kibana basic auth (optional)"),
+		usernameFile:    fs.String("kibana-username-file", "", "File holding the username for Kibana basic auth, read at every scrape (optional)"),
+		password:        fs.String("kibana-password", "", "Password for Kibana basic auth (optional)"),
+		passwordFile:    fs.String("kibana-password-file", "", "File holding the password for Kibana basic auth, read at every scrape (optional)"),
+		apiKey:          fs.String("kibana-api-key", "", "Elasticsearch API key sent as an Authorization: ApiKey header instead of basic auth, encoded or as id:key (optional)"),
+		apiKeyFile:      fs.String("kibana-api-key-file", "", "File holding the API key, read at every scrape (optional)"),
+		bearerToken:     fs.String("kibana-bearer-token", "", "Token sent as an Authorization: Bearer header instead of basic auth, e.g. a JWT for an OIDC proxy (optional)"),
+		bearerTokenFile: fs.String("kibana-bearer-token-file", "", "File holding the bearer token, read at every scrape (optional)"),
+	}
+}
+
+// fromEnv overrides the flags with KIBANA_USERNAME, KIBANA_PASSWORD,
+// KIBANA_API_KEY and KIBANA_BEARER_TOKEN
+func (f credentialFlags) fromEnv() {
+	for env, value := range map[string]*string{
+		"KIBANA_USERNAME":     f.username,
+		"KIBANA_PASSWORD":     f.password,
+		"KIBANA_API_KEY":      f.apiKey,
+		"KIBANA_BEARER_TOKEN": f.bearerToken,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*value = v
+		}
+	}
+}
+
+// set reports whether any credentials are given
+func (f credentialFlags) set() bool {
+	return *f.username != "" || *f.usernameFile != "" || *f.apiKey != "" || *f.apiKeyFile != "" ||
+		*f.bearerToken != "" || *f.bearerTokenFile != ""
+}
+
+// configure sets the credentials of config. An API key or bearer token
+// replaces the auth provider of config, which must be basic or the matching
+// provider. The credential files are read once to fail early; the collector
+// reads them again at every scrape.
+func (f credentialFlags) configure(config *collector.Config) error {
+	for name, value := range map[string][2]string{
+		"kibana-username":     {*f.username, *f.usernameFile},
+		"kibana-password":     {*f.password, *f.passwordFile},
+		"kibana-api-key":      {*f.apiKey, *f.apiKeyFile},
+		"kibana-bearer-token": {*f.bearerToken, *f.bearerTokenFile},
+	} {
+		if value[0] != "" && value[1] != "" {
+			return fmt.Errorf("--%s and --%s-file are mutually exclusive", name, name)
+		}
+	}
+	basic := *f.username != "" || *f.usernameFile != ""
+	apiKey := *f.apiKey != "" || *f.apiKeyFile != ""
+	bearer := *f.bearerToken != "" || *f.bearerTokenFile != ""
+	if (basic && apiKey) || (basic && bearer) || (apiKey && bearer) {
+		return errors.New("basic auth, --kibana-api-key and --kibana-bearer-token are mutually exclusive")
+	}
+
+	config.Username, config.UsernameFile = *f.username, *f.usernameFile
+	config.Password, config.PasswordFile = *f.password, *f.passwordFile
+	config.AuthParamFiles = nil
+	switch {
+	case apiKey:
+		if err := useProvider(config, kibana.AuthAPIKey, "api_key", *f.apiKey, *f.apiKeyFile); err != nil {
+			return fmt.Errorf("--kibana-api-key: %w", err)
+		}
+	case bearer:
+		if err := useProvider(config, kibana.AuthBearer, "token", *f.bearerToken, *f.bearerTokenFile); err != nil {
+			return fmt.Errorf("--kibana-bearer-token: %w", err)
+		}
+	}
+
+	credentials, err := readCredentialFiles(kibana.AuthConfig{Username: config.Username, Password: config.Password, Params: config.AuthParams},
+		config.UsernameFile, config.PasswordFile, config.AuthParamFiles)
+	if err != nil {
+		return fmt.Errorf("reading credentials: %w", err)
+	}
+	_, err = kibana.NewAuthProvider(config.AuthProvider, credentials)
+	return err
+}
+
+// useProvider switches config to provider, with param set to value or read
+// from file at every scrape
+func useProvider(config *collector.Config, provider, param, value, file string) error {
+	if config.AuthProvider != "" && config.AuthProvider != kibana.AuthBasic && config.AuthProvider != provider {
+		return fmt.Errorf("cannot be combined with --auth-provider=%s", config.AuthProvider)
+	}
+	config.AuthProvider = provider
+	config.AuthParams = maps.Clone(config.AuthParams)
+	if config.AuthParams == nil {
+		config.AuthParams = make(map[string]string)
+	}
+	if file != "" {
+		config.AuthParamFiles = map[string]string{param: file}
+	} else {
+		config.AuthParams[param] = value
+	}
+	return nil
+}
+
+// readCredentialFiles returns config with the credentials of the username,
+// password and auth param files read in
+func readCredentialFiles(config kibana.AuthConfig, usernameFile, passwordFile string, paramFiles map[string]string) (kibana.AuthConfig, error) {
+	var err error
+	if usernameFile != "" {
+		if config.Username, err = collector.ReadSecretFile(usernameFile); err != nil {
+			return config, err
+		}
+	}
+	if passwordFile != "" {
+		if config.Password, err = collector.ReadSecretFile(passwordFile); err != nil {
+			return config, err
+		}
+	}
+	params := maps.Clone(config.Params)
+	if params == nil && len(paramFiles) > 0 {
+		params = make(map[string]string)
+	}
+	for name, file := range paramFiles {
+		if params[name], err = collector.ReadSecretFile(file); err != nil {
+			return config, err
+		}
+	}
+	config.Params = params
+	return config, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// allCollectors are the collectors compiled into the exporter
+var allCollectors = []string{
+	collector.CollectorStatus,
+	collector.CollectorAuth,
+	collector.CollectorSpaces,
+	collector.CollectorCustom,
+	collector.CollectorUISettings,
+	collector.CollectorClusterInfo,
+	collector.CollectorDeprecations,
+	collector.CollectorSavedObjectsProbe,
+	collector.CollectorFrontend,
+	collector.CollectorOpenSearchPlugins,
+	"plugin_status",
+}
+
+// capabilities describes what this exporter instance runs, for fleet audits
+type capabilities struct {
+	Version              string          `json:"version"`
+	Commit               string          `json:"commit"`
+	Collectors           map[string]bool `json:"collectors"`
+	Features             map[string]bool `json:"features"`
+	ExperimentalFeatures []string        `json:"experimental_features"`
+}
+
+type capabilityTarget struct {
+	URL    string `json:"url"`
+	Schema string `json:"schema,omitempty"`
+}
+
+// banner logs the capabilities once at startup
+func (c capabilities) banner() {
+	var enabled []string
+	for _, name := range allCollectors {
+		if c.Collectors[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	var features []string
+	for name, on := range c.Features {
+		if on {
+			features = append(features, name)
+		}
+	}
+	slices.Sort(features)
+	log.WithFields(log.Fields{
+		"collectors":            enabled,
+		"features":              features,
+		"experimental_features": c.ExperimentalFeatures,
+	}).Info("Exporter capabilities")
+}
+
+// registerCapabilities serves /capabilities with the enabled collectors and
+// features and the schema detected for every target
+func registerCapabilities(mux *http.ServeMux, caps capabilities, targets *collector.Targets) {
+	mux.HandleFunc("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		response := struct {
+			capabilities
+			Targets []capabilityTarget `json:"targets"`
+		}{capabilities: caps, Targets: []capabilityTarget{}}
+		for _, state := range targets.States() {
+			response.Targets = append(response.Targets, capabilityTarget{URL: state.URL, Schema: state.Schema})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+}
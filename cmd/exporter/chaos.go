@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// chaosFlagPrefix marks the testing flags, which are left out of --help
+const chaosFlagPrefix = "chaos-"
+
+// chaosOptions make the exporter misbehave on purpose, so that alert rules
+// and dashboards can be tested against slow and failing scrapes
+type chaosOptions struct {
+	responseLatency     *time.Duration
+	responseFailureRate *float64
+	kibanaLatency       *time.Duration
+	kibanaFailureRate   *float64
+}
+
+func registerChaosFlags() chaosOptions {
+	o := chaosOptions{
+		responseLatency:     flag.Duration(chaosFlagPrefix+"response-latency", 0, "Delay metrics responses by up to this duration"),
+		responseFailureRate: flag.Float64(chaosFlagPrefix+"response-failure-rate", 0, "Fraction of metrics requests answered with a 500"),
+		kibanaLatency:       flag.Duration(chaosFlagPrefix+"kibana-latency", 0, "Delay replayed Kibana responses by up to this duration (--replay-dir only)"),
+		kibanaFailureRate:   flag.Float64(chaosFlagPrefix+"kibana-failure-rate", 0, "Fraction of replayed Kibana requests answered with a 503 (--replay-dir only)"),
+	}
+	flag.Usage = visibleUsage
+	return o
+}
+
+// visibleUsage prints the flags like flag.PrintDefaults, without the
+// testing flags
+func visibleUsage() {
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, chaosFlagPrefix) {
+			return
+		}
+		visible.Var(f.Value, f.Name, f.Usage)
+		visible.Lookup(f.Name).DefValue = f.DefValue
+	})
+	fmt.Fprintf(visible.Output(), "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// validate stops the exporter on invalid testing flags. Live Kibanas are
+// never made to fail, so Kibana failures need --replay-dir.
+func (o chaosOptions) validate(replayDir string) {
+	for name, rate := range map[string]float64{"response": *o.responseFailureRate, "kibana": *o.kibanaFailureRate} {
+		if rate < 0 || rate > 1 {
+			log.WithField("rate", rate).Fatalf("Invalid --%s%s-failure-rate, expected a fraction in [0, 1]", chaosFlagPrefix, name)
+		}
+	}
+	if (*o.kibanaLatency > 0 || *o.kibanaFailureRate > 0) && replayDir == "" {
+		log.Fatalf("--%skibana-latency and --%skibana-failure-rate need --replay-dir", chaosFlagPrefix, chaosFlagPrefix)
+	}
+}
+
+// kibana returns the latency and failures to inject into replayed Kibana responses
+func (o chaosOptions) kibana() collector.ChaosConfig {
+	config := collector.ChaosConfig{Latency: *o.kibanaLatency, FailureRate: *o.kibanaFailureRate}
+	if config.Latency > 0 || config.FailureRate > 0 {
+		log.WithFields(log.Fields{"latency": config.Latency.String(), "failure_rate": config.FailureRate}).Warn("Injecting failures into replayed Kibana responses")
+	}
+	return config
+}
+
+// wrap injects latency and failures into the responses of next
+func (o chaosOptions) wrap(next http.Handler) http.Handler {
+	latency, failureRate := *o.responseLatency, *o.responseFailureRate
+	if latency <= 0 && failureRate <= 0 {
+		return next
+	}
+	log.WithFields(log.Fields{"latency": latency.String(), "failure_rate": failureRate}).Warn("Injecting failures into metrics responses")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if latency > 0 {
+			select {
+			case <-time.After(rand.N(latency)):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if rand.Float64() < failureRate {
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// Exit codes of the check subcommand
+const (
+	checkHealthy     = 0
+	checkUsage       = 1
+	checkUnreachable = 2
+	checkAuth        = 3
+	checkDegraded    = 4
+)
+
+// checkResult is the --output=json form of a check
+type checkResult struct {
+	URL        string  `json:"url"`
+	Result     string  `json:"result"`
+	ExitCode   int     `json:"exit_code"`
+	Level      string  `json:"level,omitempty"`
+	Summary    string  `json:"summary,omitempty"`
+	Version    string  `json:"version,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	ErrorCode  string  `json:"error_code,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	kibanaURL := fs.String("kibana-url", "http://localhost:5601", "Kibana URL to check")
+	credentials := registerCredentialFlags(fs)
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout for the Kibana request")
+	insecureSkipVerify := fs.Bool("insecure-skip-verify", false, "Skip TLS certificate verification")
+	schema := fs.String("kibana-schema", "auto", "Format of /api/status: auto, kibana8, kibana7 or opensearch")
+	userAgent := fs.String("user-agent", defaultUserAgent(), "User-Agent header sent to Kibana")
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return checkUsage
+	}
+	if !collector.ValidSchema(*schema) {
+		fmt.Fprintf(os.Stderr, "unknown schema %q\n", *schema)
+		return checkUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		return checkUsage
+	}
+
+	// Override from environment variables if set, like the exporter does
+	if envURL := os.Getenv("KIBANA_URL"); envURL != "" {
+		*kibanaURL = envURL
+	}
+	credentials.fromEnv()
+	*kibanaURL = applyURLCredentials(*kibanaURL, credentials.username, credentials.password)
+
+	config := collector.Config{
+		KibanaURL:          *kibanaURL,
+		Timeout:            *timeout,
+		InsecureSkipVerify: *insecureSkipVerify,
+		UserAgent:          *userAgent,
+		Schema:             *schema,
+	}
+	if err := credentials.configure(&config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return checkUsage
+	}
+	c := collector.NewKibanaCollector(config)
+
+	start := time.Now()
+	status, err := c.Scrape(context.Background())
+	result := checkResult{
+		URL:        *kibanaURL,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	switch {
+	case errors.Is(err, collector.ErrAuth):
+		result.Result, result.ExitCode = "auth_failed", checkAuth
+	case err != nil:
+		result.Result, result.ExitCode = "unreachable", checkUnreachable
+	case status.Status.Overall.Level != "available":
+		result.Result, result.ExitCode = "degraded", checkDegraded
+	default:
+		result.Result, result.ExitCode = "healthy", checkHealthy
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCode = collector.ErrorCode(err)
+	} else {
+		result.Level = status.Status.Overall.Level
+		result.Summary = status.Status.Overall.Summary
+		result.Version = status.Version.Number
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	} else {
+		printCheckResult(result)
+	}
+	return result.ExitCode
+}
+
+func printCheckResult(r checkResult) {
+	switch r.ExitCode {
+	case checkHealthy, checkDegraded:
+		fmt.Printf("%s: %s is %s (version %s, %.0fms)", r.Result, r.URL, r.Level, r.Version, r.DurationMs)
+		if r.Summary != "" {
+			fmt.Printf(": %s", r.Summary)
+		}
+		fmt.Println()
+	default:
+		fmt.Printf("%s: %s [%s]: %s\n", r.Result, r.URL, r.ErrorCode, r.Error)
+	}
+}
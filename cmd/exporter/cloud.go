@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// cloudHandlingInstanceHeader names the Elastic Cloud instance that answered
+// a request proxied to the deployment
+const cloudHandlingInstanceHeader = "x-found-handling-instance"
+
+// cloudKibanaURL derives the Kibana endpoint of an Elastic Cloud deployment
+// from its Cloud ID, name:base64(domain[:port]$es_id[:port]$kibana_id[:port])
+func cloudKibanaURL(cloudID string) (string, error) {
+	_, encoded, ok := strings.Cut(cloudID, ":")
+	if !ok || encoded == "" {
+		return "", fmt.Errorf("invalid Cloud ID, expected name:base64")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if decoded, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return "", fmt.Errorf("decoding Cloud ID: %w", err)
+		}
+	}
+	parts := strings.Split(string(decoded), "$")
+	if len(parts) < 3 || parts[0] == "" || parts[2] == "" {
+		return "", fmt.Errorf("Cloud ID has no Kibana component, is Kibana enabled for the deployment?")
+	}
+
+	domain, port := splitCloudPort(parts[0], "443")
+	kibanaID, port := splitCloudPort(parts[2], port)
+	host := kibanaID + "." + domain
+	if port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	return "https://" + host, nil
+}
+
+// splitCloudPort splits an optional :port off a Cloud ID component
+func splitCloudPort(component, defaultPort string) (string, string) {
+	if host, port, ok := strings.Cut(component, ":"); ok && port != "" {
+		return host, port
+	}
+	return component, defaultPort
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// collectFilter serves ?collect[]=name requests from a per-request registry
+// holding only the named collectors of every target. Requests without the
+// parameter are passed to next. decorate wraps the registry like the one
+// serving next.
+func collectFilter(targets *collector.Targets, labels prometheus.Labels, decorate func(prometheus.Gatherer) prometheus.Gatherer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()["collect[]"]
+		if len(names) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		var registerer prometheus.Registerer = registry
+		if len(labels) > 0 {
+			registerer = prometheus.WrapRegistererWith(labels, registry)
+		}
+		if err := targets.RegisterViews(registerer, names); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		promhttp.HandlerFor(decorate(registry), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
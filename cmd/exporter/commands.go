@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// subcommands are run instead of the exporter when named as the first argument.
+// Each returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"generate-dashboard":        runGenerateDashboard,
+	"generate-alerts":           runGenerateAlerts,
+	"check":                     runCheck,
+	"report":                    runReport,
+	"print-required-privileges": runPrintRequiredPrivileges,
+	"mock-kibana":               runMockKibana,
+	"config":                    runConfig,
+	"validate-metrics":          runValidateMetrics,
+}
+
+// runSubcommand runs the subcommand named by args[0], if any, and reports whether it did
+func runSubcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		return 0, false
+	}
+	return cmd(args[1:]), true
+}
+
+// openOutput returns stdout for "" or "-", or creates the named file
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", path, err)
+	}
+	return f, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
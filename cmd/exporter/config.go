@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// configCommands are the actions of the config subcommand
+var configCommands = map[string]func(args []string) int{
+	"export-targets": runExportTargets,
+	"import-targets": runImportTargets,
+}
+
+// csvColumns are the CSV columns mapped to target fields; any other column is a label
+var csvColumns = []string{"url", "schema", "proxy", "collectors", "metric_prefix"}
+
+// runConfig runs a config action such as export-targets or import-targets
+func runConfig(args []string) int {
+	if len(args) == 0 || configCommands[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: config export-targets|import-targets [flags]")
+		return 2
+	}
+	return configCommands[args[0]](args[1:])
+}
+
+// runExportTargets writes the targets of a targets file as CSV or file_sd JSON
+func runExportTargets(args []string) int {
+	fs := flag.NewFlagSet("config export-targets", flag.ContinueOnError)
+	targetsFile := fs.String("targets-file", "", "Targets file to export")
+	format := fs.String("format", "csv", "Output format: csv or file_sd")
+	output := fs.String("output", "-", "Output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *targetsFile == "" {
+		fmt.Fprintln(os.Stderr, "--targets-file is required")
+		return 2
+	}
+	if *format != "csv" && *format != "file_sd" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	targets, err := collector.LoadTargets(*targetsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	out, err := openOutput(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer out.Close()
+	if *format == "csv" {
+		err = writeTargetsCSV(out, targets)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(sdGroups(targets))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runImportTargets converts a CSV or file_sd JSON inventory into a targets file
+func runImportTargets(args []string) int {
+	fs := flag.NewFlagSet("config import-targets", flag.ContinueOnError)
+	input := fs.String("input", "", "CSV or file_sd JSON file to import")
+	format := fs.String("format", "csv", "Input format: csv or file_sd")
+	output := fs.String("output", "-", "Targets file to write (- for stdout); other blocks of an existing file are kept")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "--input is required")
+		return 2
+	}
+	var read func(io.Reader) ([]collector.Target, error)
+	switch *format {
+	case "csv":
+		read = readTargetsCSV
+	case "file_sd":
+		read = readTargetsFileSD
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	f, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	targets, err := read(f)
+	f.Close()
+	if err == nil {
+		err = collector.ValidateTargets(targets)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *input, err)
+		return 1
+	}
+
+	if *output != "" && *output != "-" {
+		if err := persistTargets(*output, targets); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d targets into %s\n", len(targets), *output)
+		return 0
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"targets": targets}, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Stdout.Write(append(data, '\n'))
+	return 0
+}
+
+// writeTargetsCSV writes one row per target, with a column per label name.
+// Credentials, including those in the URL, and downtime windows are not exported.
+func writeTargetsCSV(w io.Writer, targets []collector.Target) error {
+	names := make(map[string]bool)
+	for _, target := range targets {
+		for name := range target.Labels {
+			names[name] = true
+		}
+	}
+	labels := slices.Sorted(maps.Keys(names))
+
+	out := csv.NewWriter(w)
+	out.Write(append(slices.Clone(csvColumns), labels...))
+	for _, target := range targets {
+		kibanaURL := target.URL
+		if u, err := url.Parse(target.URL); err == nil {
+			u.User = nil
+			kibanaURL = u.String()
+		}
+		row := []string{kibanaURL, target.Schema, target.Proxy, strings.Join(target.Collectors, ";"), target.MetricPrefix}
+		for _, name := range labels {
+			row = append(row, target.Labels[name])
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// readTargetsCSV reads targets from CSV with a header row. The url column is
+// required; collectors are separated by semicolons and empty label cells skipped.
+func readTargetsCSV(r io.Reader) ([]collector.Target, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || !slices.Contains(rows[0], "url") {
+		return nil, fmt.Errorf("missing header row with a url column")
+	}
+	header := rows[0]
+	targets := []collector.Target{}
+	for _, row := range rows[1:] {
+		var target collector.Target
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			switch header[i] {
+			case "url":
+				target.URL = value
+			case "schema":
+				target.Schema = value
+			case "proxy":
+				target.Proxy = value
+			case "collectors":
+				if value != "" {
+					target.Collectors = strings.Split(value, ";")
+				}
+			case "metric_prefix":
+				target.MetricPrefix = value
+			default:
+				if value == "" {
+					continue
+				}
+				if target.Labels == nil {
+					target.Labels = make(map[string]string)
+				}
+				target.Labels[header[i]] = value
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// readTargetsFileSD reads targets from Prometheus file_sd JSON. Each host
+// becomes a target using the __scheme__ label (http by default), or the
+// __meta_kibana_url label written by export-targets for single-host groups.
+// Other labels starting with __ are dropped.
+func readTargetsFileSD(r io.Reader) ([]collector.Target, error) {
+	var groups []sdGroup
+	if err := json.NewDecoder(r).Decode(&groups); err != nil {
+		return nil, err
+	}
+	targets := []collector.Target{}
+	for _, group := range groups {
+		scheme := group.Labels["__scheme__"]
+		if scheme == "" {
+			scheme = "http"
+		}
+		var labels map[string]string
+		for name, value := range group.Labels {
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[name] = value
+		}
+		for _, host := range group.Targets {
+			kibanaURL := scheme + "://" + host
+			if metaURL := group.Labels["__meta_kibana_url"]; metaURL != "" && len(group.Targets) == 1 {
+				kibanaURL = metaURL
+			}
+			targets = append(targets, collector.Target{URL: kibanaURL, Labels: maps.Clone(labels)})
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+	log "github.com/sirupsen/logrus"
+)
+
+// urlCredentials strips the userinfo from rawURL so that it never reaches
+// logs or metrics, and returns it as a username and password
+func urlCredentials(rawURL string) (clean, username, password string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL, "", ""
+	}
+	username = u.User.Username()
+	password, _ = u.User.Password()
+	u.User = nil
+	return u.String(), username, password
+}
+
+// applyURLCredentials strips the userinfo from rawURL and uses it as the
+// basic auth credentials unless a username is already configured
+func applyURLCredentials(rawURL string, username, password *string) string {
+	clean, user, pass := urlCredentials(rawURL)
+	switch {
+	case user == "":
+	case *username == "":
+		*username, *password = user, pass
+	case *username != user || *password != pass:
+		log.WithField("kibana_url", clean).Warn("Ignoring credentials in Kibana URL, --kibana-username is already set")
+	}
+	return clean
+}
+
+// applyTargetCredentials strips the userinfo from the URL of each target and
+// uses it as that target's own credentials, unless the target sets a
+// username. Like any per-target credentials they win over the shared ones.
+func applyTargetCredentials(targets []collector.Target) {
+	for i := range targets {
+		clean, user, pass := urlCredentials(targets[i].URL)
+		targets[i].URL = clean
+		switch {
+		case user == "":
+		case targets[i].Username == "":
+			targets[i].Username, targets[i].Password = user, pass
+		case targets[i].Username != user || targets[i].Password != pass:
+			log.WithField("kibana_url", clean).Warn("Ignoring credentials in Kibana URL, the target sets a username")
+		}
+	}
+}
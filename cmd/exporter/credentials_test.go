@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTargetsFileURLCredentials(t *testing.T) {
+	m := &mockKibana{level: "available", version: "8.15.0", statusCode: http.StatusOK, started: time.Now(), username: "u", password: "p"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", m.api(m.status))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "targets.json")
+	withUserinfo := strings.Replace(server.URL, "http://", "http://u:p@", 1)
+	data := `{"targets": [{"url": "` + withUserinfo + `", "labels": {"kibana_instance": "a"}}]}`
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := collector.LoadTargets(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyTargetCredentials(targets)
+	if targets[0].URL != server.URL {
+		t.Errorf("URL = %q, want the credentials stripped: %q", targets[0].URL, server.URL)
+	}
+
+	// Shared credentials are configured as with no credential flags
+	var config collector.Config
+	if err := registerCredentialFlags(flag.NewFlagSet("test", flag.ContinueOnError)).configure(&config); err != nil {
+		t.Fatal(err)
+	}
+	config.Timeout = 5 * time.Second
+	registry := prometheus.NewRegistry()
+	if err := collector.NewTargets(registry, config).Set(targets); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "kibana_up" {
+			continue
+		}
+		if up := family.GetMetric()[0].GetGauge().GetValue(); up != 1 {
+			t.Errorf("kibana_up = %v, want 1", up)
+		}
+		return
+	}
+	t.Error("kibana_up not exported")
+}
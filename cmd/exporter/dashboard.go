@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dashboardPanel describes one generated Grafana panel
+type dashboardPanel struct {
+	title   string
+	kind    string // stat or timeseries
+	unit    string
+	width   int
+	queries []dashboardQuery
+}
+
+type dashboardQuery struct {
+	expr   string // %s is replaced by the label selector
+	legend string
+}
+
+type dashboardRow struct {
+	title  string
+	panels []dashboardPanel
+}
+
+// dashboardRows covers every metric family the exporter produces
+var dashboardRows = []dashboardRow{
+	{"Overview", []dashboardPanel{
+		{"Kibana Up", "stat", "none", 4, []dashboardQuery{{"kibana_up{%s}", "{{instance}}"}}},
+		{"Overall Status", "stat", "none", 4, []dashboardQuery{{"kibana_status_overall{%s}", "{{instance}}"}}},
+		{"Elasticsearch", "stat", "none", 4, []dashboardQuery{{"kibana_status_elasticsearch{%s}", "{{instance}}"}}},
+		{"Saved Objects", "stat", "none", 4, []dashboardQuery{{"kibana_status_saved_objects{%s}", "{{instance}}"}}},
+		{"Uptime", "stat", "s", 4, []dashboardQuery{{"kibana_process_uptime_seconds{%s}", "{{instance}}"}}},
+		{"Concurrent Connections", "stat", "short", 4, []dashboardQuery{{"kibana_concurrent_connections_total{%s}", "{{instance}}"}}},
+		{"Core Services", "timeseries", "none", 24, []dashboardQuery{{"kibana_status_core{%s}", "{{instance}} {{name}}"}}},
+	}},
+	{"Memory", []dashboardPanel{
+		{"Heap", "timeseries", "bytes", 12, []dashboardQuery{
+			{"kibana_heap_used_bytes{%s}", "{{instance}} used"},
+			{"kibana_heap_total_bytes{%s}", "{{instance}} total"},
+			{"kibana_heap_size_limit_bytes{%s}", "{{instance}} limit"},
+		}},
+		{"Heap Usage", "timeseries", "percentunit", 6, []dashboardQuery{
+			{"kibana_heap_used_bytes{%[1]s} / kibana_heap_size_limit_bytes{%[1]s}", "{{instance}}"},
+		}},
+		{"Resident Set", "timeseries", "bytes", 6, []dashboardQuery{{"kibana_memory_resident_set_bytes{%s}", "{{instance}}"}}},
+	}},
+	{"Performance", []dashboardPanel{
+		{"Response Time", "timeseries", "s", 8, []dashboardQuery{{"kibana_response_time_seconds{%s}", "{{instance}} {{quantile}}"}}},
+		{"Event Loop Delay", "timeseries", "s", 8, []dashboardQuery{{"kibana_event_loop_delay_seconds{%s}", "{{instance}}"}}},
+		{"Requests", "timeseries", "reqps", 8, []dashboardQuery{{"kibana_requests_per_second{%s}", "{{instance}}"}}},
+	}},
+	{"Operating System", []dashboardPanel{
+		{"CPU", "timeseries", "percent", 8, []dashboardQuery{{"kibana_os_cpu_percent{%s}", "{{instance}}"}}},
+		{"Load Average", "timeseries", "short", 8, []dashboardQuery{
+			{"kibana_os_load_average_1m{%s}", "{{instance}} 1m"},
+			{"kibana_os_load_average_5m{%s}", "{{instance}} 5m"},
+			{"kibana_os_load_average_15m{%s}", "{{instance}} 15m"},
+		}},
+		{"Memory", "timeseries", "bytes", 8, []dashboardQuery{
+			{"kibana_os_memory_used_bytes{%s}", "{{instance}} used"},
+			{"kibana_os_memory_free_bytes{%s}", "{{instance}} free"},
+			{"kibana_os_memory_total_bytes{%s}", "{{instance}} total"},
+		}},
+	}},
+	{"Exporter", []dashboardPanel{
+		{"Scrape Duration", "timeseries", "s", 8, []dashboardQuery{{"kibana_scrape_duration_seconds{%s}", "{{instance}}"}}},
+		{"Payload Size", "timeseries", "bytes", 8, []dashboardQuery{{"kibana_exporter_payload_bytes{%s}", "{{instance}} {{encoding}}"}}},
+		{"Throttled Responses", "timeseries", "short", 8, []dashboardQuery{{"increase(kibana_exporter_throttled_total{%s}[5m])", "{{instance}}"}}},
+		{"Build", "table", "none", 24, []dashboardQuery{{"kibana_exporter_build_info{%s}", ""}}},
+	}},
+}
+
+func runGenerateDashboard(args []string) int {
+	fs := flag.NewFlagSet("generate-dashboard", flag.ContinueOnError)
+	title := fs.String("title", "Kibana", "Dashboard title")
+	uid := fs.String("uid", "kibana-prometheus-exporter", "Dashboard UID")
+	datasource := fs.String("datasource", "", "Prometheus datasource UID (default: a $datasource dashboard variable)")
+	variables := fs.String("variables", "job,instance", "Comma-separated labels to expose as dashboard filter variables")
+	selector := fs.String("selector", "", `Additional fixed label matchers applied to every query, e.g. 'cluster="prod"'`)
+	output := fs.String("output", "-", "Output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dashboard := buildDashboard(*title, *uid, *datasource, splitList(*variables), *selector)
+
+	out, err := openOutput(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dashboard); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func buildDashboard(title, uid, datasource string, variables []string, selector string) map[string]interface{} {
+	ds := map[string]interface{}{"type": "prometheus", "uid": datasource}
+	var templates []interface{}
+	if datasource == "" {
+		ds["uid"] = "${datasource}"
+		templates = append(templates, map[string]interface{}{
+			"name": "datasource", "type": "datasource", "query": "prometheus", "hide": 0,
+			"current": map[string]interface{}{},
+		})
+	}
+
+	matchers := make([]string, 0, len(variables)+1)
+	for _, v := range variables {
+		matchers = append(matchers, fmt.Sprintf(`%s=~"$%s"`, v, v))
+		templates = append(templates, map[string]interface{}{
+			"name":       v,
+			"type":       "query",
+			"datasource": ds,
+			"query":      map[string]interface{}{"query": fmt.Sprintf("label_values(kibana_up, %s)", v), "refId": "StandardVariableQuery"},
+			"definition": fmt.Sprintf("label_values(kibana_up, %s)", v),
+			"refresh":    2,
+			"includeAll": true,
+			"multi":      true,
+			"allValue":   ".*",
+			"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+			"sort":       1,
+		})
+	}
+	if selector != "" {
+		matchers = append(matchers, selector)
+	}
+	labelSelector := strings.Join(matchers, ", ")
+
+	var panels []interface{}
+	id, y := 1, 0
+	for _, row := range dashboardRows {
+		panels = append(panels, map[string]interface{}{
+			"type": "row", "title": row.title, "id": id, "collapsed": false, "panels": []interface{}{},
+			"gridPos": map[string]int{"h": 1, "w": 24, "x": 0, "y": y},
+		})
+		id++
+		y++
+
+		x, rowHeight := 0, 0
+		for _, p := range row.panels {
+			height := 8
+			if p.kind == "stat" {
+				height = 4
+			}
+			if x+p.width > 24 {
+				x = 0
+				y += rowHeight
+				rowHeight = 0
+			}
+
+			targets := make([]interface{}, 0, len(p.queries))
+			for i, q := range p.queries {
+				targets = append(targets, map[string]interface{}{
+					"expr":         fmt.Sprintf(q.expr, labelSelector),
+					"legendFormat": q.legend,
+					"refId":        string(rune('A' + i)),
+				})
+			}
+			panels = append(panels, map[string]interface{}{
+				"id":          id,
+				"type":        p.kind,
+				"title":       p.title,
+				"datasource":  ds,
+				"gridPos":     map[string]int{"h": height, "w": p.width, "x": x, "y": y},
+				"fieldConfig": map[string]interface{}{"defaults": map[string]interface{}{"unit": p.unit}, "overrides": []interface{}{}},
+				"targets":     targets,
+			})
+			id++
+			x += p.width
+			if height > rowHeight {
+				rowHeight = height
+			}
+		}
+		y += rowHeight
+	}
+
+	return map[string]interface{}{
+		"title":         title,
+		"uid":           uid,
+		"editable":      true,
+		"schemaVersion": 38,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-1h", "to": "now"},
+		"tags":          []string{"kibana", "elastic", "prometheus"},
+		"templating":    map[string]interface{}{"list": templates},
+		"panels":        panels,
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Experimental features, enabled with --enable-feature
+const (
+	featurePerSpace         = "per-space"
+	featureNativeHistograms = "native-histograms"
+)
+
+var experimentalFeatures = []struct {
+	name        string
+	description string
+}{
+	{featurePerSpace, "per-space collectors (--spaces)"},
+	{featureNativeHistograms, "native histogram buckets on exporter histograms"},
+}
+
+// featureSet holds the experimental features enabled at startup
+type featureSet map[string]bool
+
+// parseFeatures parses a comma-separated --enable-feature value and rejects
+// unknown names so that typos do not silently leave a feature off
+func parseFeatures(value string) (featureSet, error) {
+	enabled := make(featureSet)
+	for _, name := range splitList(value) {
+		if !knownFeature(name) {
+			return nil, fmt.Errorf("unknown feature %q, expected one of %s", name, strings.Join(featureNames(), ", "))
+		}
+		enabled[name] = true
+	}
+	return enabled, nil
+}
+
+func (f featureSet) enabled(name string) bool {
+	return f[name]
+}
+
+// names returns the enabled features in table order
+func (f featureSet) names() []string {
+	names := []string{}
+	for _, feature := range experimentalFeatures {
+		if f[feature.name] {
+			names = append(names, feature.name)
+		}
+	}
+	return names
+}
+
+// warn logs every enabled feature, since experimental behavior may change
+// between releases
+func (f featureSet) warn() {
+	for _, feature := range experimentalFeatures {
+		if f[feature.name] {
+			log.WithField("feature", feature.name).Warnf("Experimental feature enabled: %s", feature.description)
+		}
+	}
+}
+
+func knownFeature(name string) bool {
+	for _, feature := range experimentalFeatures {
+		if feature.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func featureNames() []string {
+	names := make([]string, 0, len(experimentalFeatures))
+	for _, feature := range experimentalFeatures {
+		names = append(names, feature.name)
+	}
+	return names
+}
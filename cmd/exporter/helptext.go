@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// loadHelpOverrides reads a JSON object mapping metric family names to HELP texts
+func loadHelpOverrides(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var help map[string]string
+	if err := json.Unmarshal(data, &help); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return help, nil
+}
+
+// helpGatherer replaces the HELP text of the metric families in help
+type helpGatherer struct {
+	gatherer prometheus.Gatherer
+	help     map[string]string
+}
+
+// withHelp applies help overrides to g, if there are any
+func withHelp(g prometheus.Gatherer, help map[string]string) prometheus.Gatherer {
+	if len(help) == 0 {
+		return g
+	}
+	return helpGatherer{gatherer: g, help: help}
+}
+
+func (h helpGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := h.gatherer.Gather()
+	for _, family := range families {
+		if help, ok := h.help[family.GetName()]; ok {
+			family.Help = &help
+		}
+	}
+	return families, err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// historyTarget is the scrape history of one target at /history
+type historyTarget struct {
+	URL     string             `json:"url"`
+	Samples []collector.Sample `json:"samples"`
+}
+
+// registerHistory serves the recent scrape outcomes of every target at
+// /history, oldest first; ?target=<url> limits the response to one target
+func registerHistory(mux *http.ServeMux, targets *collector.Targets) {
+	mux.HandleFunc("/history", func(w http.ResponseWriter, r *http.Request) {
+		filter := r.URL.Query().Get("target")
+		result := []historyTarget{}
+		for _, c := range targets.Collectors() {
+			url := c.State().URL
+			if filter != "" && url != filter {
+				continue
+			}
+			result = append(result, historyTarget{URL: url, Samples: c.History()})
+		}
+		if filter != "" && len(result) == 0 {
+			http.Error(w, "unknown target "+filter, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+}
@@ -0,0 +1,120 @@
+//go:build !minimal
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/kube"
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/notify"
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/plugin"
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// The integrations below reach beyond Kibana: Kubernetes discovery,
+// webhooks, tracing and plugins. Building with the minimal tag replaces
+// them with integrations_minimal.go.
+
+// buildFlavor is reported in the build info
+const buildFlavor = "full"
+
+// newStateWebhook posts target state changes to url
+func newStateWebhook(url string, debounce time.Duration) (func(collector.StateChange), error) {
+	return notify.NewWebhook(url, debounce).Notify, nil
+}
+
+// newTracer exports spans to the OTLP endpoint, or returns nil if it is empty
+func newTracer(endpoint, serviceName string, sampleRatio float64) (collector.Tracer, error) {
+	tracer := tracing.New(tracing.Config{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		SampleRatio: sampleRatio,
+	})
+	if tracer == nil {
+		return nil, nil
+	}
+	return otlpTracer{tracer}, nil
+}
+
+// otlpTracer adapts a tracing.Tracer to collector.Tracer
+type otlpTracer struct {
+	*tracing.Tracer
+}
+
+func (t otlpTracer) Start(ctx context.Context, name string, kind int) (context.Context, collector.Span) {
+	// Spans dropped by sampling are nil, which tracing.Span handles
+	return t.Tracer.Start(ctx, name, kind)
+}
+
+// readDownwardLabels reads the pod labels of the Downward API volume at dir
+func readDownwardLabels(dir string) (prometheus.Labels, error) {
+	return kube.DownwardLabels(dir), nil
+}
+
+// watchKubernetesService keeps targets in sync with the ready pods of a
+// namespace/name Service, scraping them with scheme
+func watchKubernetesService(targets *collector.Targets, service, port, scheme string) error {
+	watcher, err := newEndpointsWatcher(service, port)
+	if err != nil {
+		return err
+	}
+	go watcher.Run(context.Background(), func(endpoints []kube.Endpoint) {
+		if err := targets.Set(endpointTargets(scheme, endpoints)); err != nil {
+			log.WithError(err).Error("Failed to register Kibana pods")
+		}
+	})
+	return nil
+}
+
+// registerPlugins registers a collector running the plugins of file
+func registerPlugins(registerer prometheus.Registerer, file string) error {
+	plugins, err := plugin.Load(file)
+	if err != nil {
+		return err
+	}
+	log.WithField("plugins", len(plugins)).Info("Loaded plugins")
+	registerer.MustRegister(plugin.NewCollector(plugins))
+	return nil
+}
+
+func newEndpointsWatcher(service, port string) (*kube.EndpointsWatcher, error) {
+	namespace, name, ok := strings.Cut(service, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("--kubernetes-service must be namespace/name, got %q", service)
+	}
+	client, err := kube.NewInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("service", service).Info("Discovering Kibana pods from Service endpoints")
+	return &kube.EndpointsWatcher{
+		Client:    client,
+		Namespace: namespace,
+		Service:   name,
+		Port:      port,
+	}, nil
+}
+
+// endpointTargets turns Service endpoints into per-pod scrape targets
+func endpointTargets(scheme string, endpoints []kube.Endpoint) []collector.Target {
+	result := make([]collector.Target, 0, len(endpoints))
+	for _, ep := range endpoints {
+		result = append(result, collector.Target{
+			URL: scheme + "://" + net.JoinHostPort(ep.IP, strconv.Itoa(ep.Port)),
+			Labels: map[string]string{
+				"kibana_pod":  ep.PodName,
+				"kibana_node": ep.NodeName,
+			},
+		})
+	}
+	return result
+}
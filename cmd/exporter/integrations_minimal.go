@@ -0,0 +1,41 @@
+//go:build minimal
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// buildFlavor is reported in the build info
+const buildFlavor = "minimal"
+
+// errMinimalBuild is returned when a flag needs an integration left out of
+// the minimal build
+var errMinimalBuild = errors.New("not available in the minimal build")
+
+func newStateWebhook(url string, debounce time.Duration) (func(collector.StateChange), error) {
+	return nil, errMinimalBuild
+}
+
+func newTracer(endpoint, serviceName string, sampleRatio float64) (collector.Tracer, error) {
+	if endpoint != "" {
+		return nil, errMinimalBuild
+	}
+	return nil, nil
+}
+
+func readDownwardLabels(dir string) (prometheus.Labels, error) {
+	return nil, errMinimalBuild
+}
+
+func watchKubernetesService(targets *collector.Targets, service, port, scheme string) error {
+	return errMinimalBuild
+}
+
+func registerPlugins(registerer prometheus.Registerer, file string) error {
+	return errMinimalBuild
+}
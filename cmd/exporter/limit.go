@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// concurrencyLimiter caps how many metrics requests are served at once.
+// Excess requests wait up to queueTimeout for a slot and are then rejected with 503.
+type concurrencyLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	rejected     prometheus.Counter
+}
+
+func newConcurrencyLimiter(limit int, queueTimeout time.Duration, registerer prometheus.Registerer) *concurrencyLimiter {
+	rejected := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kibana_exporter",
+		Name:      "scrape_requests_rejected_total",
+		Help:      "Number of metrics requests rejected because too many were already in flight",
+	})
+	registerer.MustRegister(rejected)
+
+	return &concurrencyLimiter{
+		slots:        make(chan struct{}, limit),
+		queueTimeout: queueTimeout,
+		rejected:     rejected,
+	}
+}
+
+func (l *concurrencyLimiter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			l.rejected.Inc()
+			log.WithField("remote_addr", r.RemoteAddr).Warn("Rejecting metrics request, too many scrapes in flight")
+			http.Error(w, "too many concurrent scrapes", http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-l.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *concurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+//go:embed static/dashboard.html
+var dashboardHTML []byte
+
+type dashboardTarget struct {
+	State   collector.ScrapeState `json:"state"`
+	History []collector.Sample    `json:"history"`
+}
+
+type dashboardData struct {
+	Version       string            `json:"version"`
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	Targets       []dashboardTarget `json:"targets"`
+}
+
+// registerDashboard serves the built-in live dashboard and its data
+func registerDashboard(mux *http.ServeMux, targets *collector.Targets) {
+	mux.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardHTML)
+	})
+	mux.HandleFunc("/dashboard/data", func(w http.ResponseWriter, r *http.Request) {
+		data := dashboardData{
+			Version:       version,
+			UptimeSeconds: time.Since(startTime).Seconds(),
+		}
+		for _, c := range targets.Collectors() {
+			data.Targets = append(data.Targets, dashboardTarget{State: c.State(), History: c.History()})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+	})
+}
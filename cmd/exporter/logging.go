@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// logOutputConfig selects where log entries are written
+type logOutputConfig struct {
+	Output        string
+	File          string
+	SyslogNetwork string
+	SyslogAddress string
+}
+
+func configureLogging(level, format string) {
+	// Set log level
+	switch level {
+	case "debug":
+		log.SetLevel(log.DebugLevel)
+	case "info":
+		log.SetLevel(log.InfoLevel)
+	case "warn":
+		log.SetLevel(log.WarnLevel)
+	case "error":
+		log.SetLevel(log.ErrorLevel)
+	default:
+		log.SetLevel(log.InfoLevel)
+	}
+
+	// Set log format
+	if format == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+	} else {
+		log.SetFormatter(&log.TextFormatter{
+			FullTimestamp: true,
+		})
+	}
+}
+
+func configureLogOutput(config logOutputConfig) error {
+	switch config.Output {
+	case "", "stderr":
+		log.SetOutput(os.Stderr)
+	case "file":
+		if config.File == "" {
+			return fmt.Errorf("--log-file is required when --log-output=file")
+		}
+		f, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return fmt.Errorf("opening log file: %w", err)
+		}
+		log.SetOutput(f)
+	case "syslog":
+		hook, err := newSyslogHook(config.SyslogNetwork, config.SyslogAddress)
+		if err != nil {
+			return fmt.Errorf("connecting to syslog: %w", err)
+		}
+		log.AddHook(hook)
+		log.SetOutput(io.Discard)
+	case "journald":
+		hook, err := newJournaldHook()
+		if err != nil {
+			return fmt.Errorf("connecting to journald: %w", err)
+		}
+		log.AddHook(hook)
+		log.SetOutput(io.Discard)
+	default:
+		return fmt.Errorf("unknown log output %q", config.Output)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func newSyslogHook(network, address string) (log.Hook, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
+
+func newJournaldHook() (log.Hook, error) {
+	return nil, errors.New("journald output is not supported on this platform")
+}
@@ -0,0 +1,110 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	logsyslog "github.com/sirupsen/logrus/hooks/syslog"
+)
+
+const (
+	journaldSocket = "/run/systemd/journal/socket"
+	syslogTag      = "kibana-exporter"
+	syslogPriority = syslog.LOG_INFO | syslog.LOG_DAEMON
+)
+
+func newSyslogHook(network, address string) (log.Hook, error) {
+	return logsyslog.NewSyslogHook(network, address, syslogPriority, syslogTag)
+}
+
+// journaldHook writes entries to the systemd journal using its native datagram protocol,
+// keeping logrus fields as structured journal fields
+type journaldHook struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func newJournaldHook() (log.Hook, error) {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	addr := &net.UnixAddr{Name: journaldSocket, Net: "unixgram"}
+	if _, err := os.Stat(journaldSocket); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &journaldHook{conn: conn, addr: addr}, nil
+}
+
+func (h *journaldHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *journaldHook) Fire(entry *log.Entry) error {
+	var b strings.Builder
+	writeJournalField(&b, "MESSAGE", entry.Message)
+	writeJournalField(&b, "PRIORITY", strconv.Itoa(journalPriority(entry.Level)))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", syslogTag)
+	for k, v := range entry.Data {
+		if name := journalFieldName(k); name != "" {
+			writeJournalField(&b, name, fmt.Sprint(v))
+		}
+	}
+
+	_, err := h.conn.WriteToUnix([]byte(b.String()), h.addr)
+	return err
+}
+
+// writeJournalField encodes a field, using the length-prefixed form for multi-line values
+func writeJournalField(b *strings.Builder, key, value string) {
+	if !strings.Contains(value, "\n") {
+		b.WriteString(key + "=" + value + "\n")
+		return
+	}
+	b.WriteString(key + "\n")
+	n := uint64(len(value))
+	for i := 0; i < 8; i++ {
+		b.WriteByte(byte(n >> (8 * i)))
+	}
+	b.WriteString(value + "\n")
+}
+
+// journalFieldName converts a logrus field key into a valid journal field name
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	return strings.TrimLeft(name, "_0123456789")
+}
+
+func journalPriority(level log.Level) int {
+	switch level {
+	case log.PanicLevel:
+		return 0
+	case log.FatalLevel:
+		return 2
+	case log.ErrorLevel:
+		return 3
+	case log.WarnLevel:
+		return 4
+	case log.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
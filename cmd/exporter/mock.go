@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockKibana serves fake Kibana API responses whose status and latency can
+// be changed at runtime through /_mock
+type mockKibana struct {
+	mutex      sync.Mutex
+	level      string
+	latency    time.Duration
+	jitter     time.Duration
+	clockSkew  time.Duration
+	statusCode int
+	version    string
+	username   string
+	password   string
+
+	started  time.Time
+	heapUsed float64
+	// requests counts the requests of the current collection interval and
+	// sampled those of the last completed one, which is what Kibana reports
+	requests    int64
+	sampled     int64
+	collectedAt time.Time
+}
+
+// mockCollectionInterval is how often the mock, like Kibana, samples its metrics
+const mockCollectionInterval = 5 * time.Second
+
+var mockLevels = map[string]string{
+	"available":   "All services are available",
+	"degraded":    "1 service is degraded: savedObjects",
+	"unavailable": "1 service is unavailable: elasticsearch",
+}
+
+func runMockKibana(args []string) int {
+	fs := flag.NewFlagSet("mock-kibana", flag.ContinueOnError)
+	listenAddr := fs.String("listen-address", ":5601", "Address to listen on")
+	level := fs.String("level", "available", "Overall status level: available, degraded or unavailable")
+	latency := fs.Duration("latency", 0, "Delay added to every response")
+	jitter := fs.Duration("latency-jitter", 0, "Random extra delay of up to this duration")
+	clockSkew := fs.Duration("clock-skew", 0, "Offset of the mock's clock in collected_at, e.g. -30s")
+	statusCode := fs.Int("status-code", http.StatusOK, "HTTP status of API responses, e.g. 503 to simulate migrations")
+	kibanaVersion := fs.String("kibana-version", "8.15.0", "Version reported by the mock")
+	auth := fs.String("auth", "", "Require basic auth as user:password (optional)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	now := time.Now()
+	m := &mockKibana{
+		latency:     *latency,
+		jitter:      *jitter,
+		clockSkew:   *clockSkew,
+		statusCode:  *statusCode,
+		version:     *kibanaVersion,
+		started:     now,
+		heapUsed:    300 << 20,
+		collectedAt: now,
+	}
+	if err := m.setLevel(*level); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *auth != "" {
+		user, pass, ok := strings.Cut(*auth, ":")
+		if !ok {
+			fmt.Fprintln(os.Stderr, "--auth must be user:password")
+			return 2
+		}
+		m.username, m.password = user, pass
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", m.api(m.status))
+	mux.HandleFunc("/api/stats", m.api(m.stats))
+	mux.HandleFunc("/api/task_manager/_health", m.api(m.taskManager))
+	mux.HandleFunc("/internal/security/me", m.api(m.securityMe))
+	mux.HandleFunc("/api/kibana/settings", m.api(m.uiSettings))
+	mux.HandleFunc("/api/deprecations/", m.api(m.kibanaDeprecations))
+	mux.HandleFunc("/api/upgrade_assistant/es_deprecations", m.api(m.esDeprecations))
+	mux.HandleFunc("/api/upgrade_assistant/status", m.api(m.upgradeStatus))
+	mux.HandleFunc("/api/saved_objects/_find", m.api(m.findSavedObjects))
+	mux.HandleFunc("/api/alerting/monitors", m.api(m.alertingMonitors))
+	mux.HandleFunc("/api/alerting/alerts", m.api(m.alertingAlerts))
+	mux.HandleFunc("/api/anomaly_detectors/detectors", m.api(m.anomalyDetectors))
+	mux.HandleFunc("/api/ism/policies", m.api(m.ismPolicies))
+	mux.HandleFunc("/api/ism/managedIndices", m.api(m.ismManagedIndices))
+	mux.HandleFunc("/login", m.login)
+	mux.HandleFunc("/_mock", m.control)
+
+	fmt.Fprintf(os.Stderr, "Mock Kibana %s listening on %s (level %s)\n", m.version, *listenAddr, *level)
+	if err := http.ListenAndServe(*listenAddr, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func (m *mockKibana) setLevel(level string) error {
+	if _, ok := mockLevels[level]; !ok {
+		return fmt.Errorf("unknown level %q, expected available, degraded or unavailable", level)
+	}
+	m.level = level
+	return nil
+}
+
+// login serves a stand-in for Kibana's login page, which needs no credentials
+func (m *mockKibana) login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, "<!DOCTYPE html><html><head><title>Elastic</title></head><body><div id=\"kibana-body\"></div></body></html>")
+}
+
+// control changes the mock at runtime, e.g. POST /_mock?level=degraded&latency=2s
+func (m *mockKibana) control(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	query := r.URL.Query()
+	if level := query.Get("level"); level != "" {
+		if err := m.setLevel(level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	for name, target := range map[string]*time.Duration{"latency": &m.latency, "latency_jitter": &m.jitter, "clock_skew": &m.clockSkew} {
+		if value := query.Get(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %v", name, err), http.StatusBadRequest)
+				return
+			}
+			*target = d
+		}
+	}
+	if value := query.Get("status_code"); value != "" {
+		code, err := strconv.Atoi(value)
+		if err != nil || code < 100 || code > 599 {
+			http.Error(w, "invalid status_code", http.StatusBadRequest)
+			return
+		}
+		m.statusCode = code
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"level":          m.level,
+		"latency":        m.latency.String(),
+		"latency_jitter": m.jitter.String(),
+		"clock_skew":     m.clockSkew.String(),
+		"status_code":    m.statusCode,
+	})
+}
+
+// api wraps a response generator with auth, latency and status code simulation
+func (m *mockKibana) api(generate func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		delay := m.latency
+		if m.jitter > 0 {
+			delay += rand.N(m.jitter)
+		}
+		statusCode := m.statusCode
+		m.requests++
+		m.mutex.Unlock()
+
+		time.Sleep(delay)
+
+		// Kibana names itself on every response
+		w.Header().Set("kbn-name", "mock-kibana")
+		if m.username != "" {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != m.username || pass != m.password {
+				http.Error(w, `{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+		}
+		if statusCode == http.StatusServiceUnavailable {
+			http.Error(w, "Kibana server is not ready yet", statusCode)
+			return
+		}
+		if statusCode != http.StatusOK {
+			http.Error(w, fmt.Sprintf(`{"statusCode":%d,"error":"%s"}`, statusCode, http.StatusText(statusCode)), statusCode)
+			return
+		}
+
+		m.mutex.Lock()
+		body := generate()
+		m.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// savedObjectsStatus reports migrations as Kibana does: completed with
+// per-index results when available, still running when degraded
+func (m *mockKibana) savedObjectsStatus(level string) interface{} {
+	if level != "available" {
+		return map[string]interface{}{
+			"level":   level,
+			"summary": "SavedObjects service is running migrations",
+			"detail":  "Waiting for outdated documents to be migrated",
+		}
+	}
+	return map[string]interface{}{
+		"level":   level,
+		"summary": "SavedObjects service has completed migrations and is available",
+		"meta": map[string]interface{}{
+			"migratedIndices": map[string]int{"migrated": 0, "skipped": 0, "patched": 7},
+		},
+	}
+}
+
+// sample returns the request count and collection time of the last
+// completed collection interval
+func (m *mockKibana) sample() (int64, time.Time) {
+	if now := time.Now(); now.Sub(m.collectedAt) >= mockCollectionInterval {
+		m.sampled, m.requests = m.requests, 0
+		m.collectedAt = now
+	}
+	return m.sampled, m.collectedAt
+}
+
+func (m *mockKibana) status() interface{} {
+	// Let heap usage wander so that dashboards show movement
+	m.heapUsed += (rand.Float64() - 0.5) * (20 << 20)
+	m.heapUsed = min(max(m.heapUsed, 100<<20), 3<<30)
+
+	service := func(level string) map[string]string {
+		return map[string]string{"level": level, "summary": mockLevels[level]}
+	}
+	coreLevel := map[string]string{"elasticsearch": "available", "savedObjects": "available"}
+	switch m.level {
+	case "degraded":
+		coreLevel["savedObjects"] = "degraded"
+	case "unavailable":
+		coreLevel["elasticsearch"] = "unavailable"
+	}
+
+	uptime := float64(time.Since(m.started).Milliseconds())
+	requests, collectedAt := m.sample()
+	return map[string]interface{}{
+		"name": "mock-kibana",
+		"uuid": "00000000-0000-0000-0000-000000000000",
+		"version": map[string]interface{}{
+			"number": m.version, "build_hash": "mock", "build_number": 1, "build_snapshot": false,
+		},
+		"status": map[string]interface{}{
+			"overall": service(m.level),
+			"core": map[string]interface{}{
+				"elasticsearch": service(coreLevel["elasticsearch"]),
+				"savedObjects":  m.savedObjectsStatus(coreLevel["savedObjects"]),
+			},
+			"plugins": map[string]interface{}{},
+		},
+		"metrics": map[string]interface{}{
+			"collected_at":                  collectedAt.Add(m.clockSkew).UTC().Format(time.RFC3339),
+			"collection_interval_in_millis": mockCollectionInterval.Milliseconds(),
+			"concurrent_connections":        rand.IntN(20),
+			"process": map[string]interface{}{
+				"memory": map[string]interface{}{
+					"heap": map[string]interface{}{
+						"total_in_bytes": int64(m.heapUsed) + 64<<20,
+						"used_in_bytes":  int64(m.heapUsed),
+						"size_limit":     int64(4 << 30),
+					},
+					"resident_set_size_in_bytes": int64(m.heapUsed) + 200<<20,
+				},
+				"event_loop_delay": 5 + rand.Float64()*20,
+				"uptime_in_millis": uptime,
+			},
+			"os": map[string]interface{}{
+				"load": map[string]interface{}{"1m": rand.Float64() * 2, "5m": 1.0, "15m": 0.8},
+				"memory": map[string]interface{}{
+					"total_in_bytes": int64(16 << 30), "free_in_bytes": int64(8 << 30), "used_in_bytes": int64(8 << 30),
+				},
+			},
+			"requests": map[string]interface{}{
+				"total":        requests,
+				"disconnects":  0,
+				"status_codes": map[string]int64{"200": requests},
+			},
+			"response_times": map[string]interface{}{
+				"avg_in_millis": 20 + rand.Float64()*30,
+				"max_in_millis": 100 + rand.Float64()*400,
+				"p95_in_millis": 60 + rand.Float64()*40,
+				"p99_in_millis": 80 + rand.Float64()*100,
+			},
+		},
+	}
+}
+
+func (m *mockKibana) securityMe() interface{} {
+	if m.username == "" {
+		return map[string]interface{}{
+			"username":                "anonymous",
+			"authentication_provider": map[string]string{"type": "anonymous", "name": "anonymous1"},
+		}
+	}
+	return map[string]interface{}{
+		"username":                m.username,
+		"authentication_provider": map[string]string{"type": "basic", "name": "basic"},
+	}
+}
+
+func (m *mockKibana) uiSettings() interface{} {
+	return map[string]interface{}{
+		"settings": map[string]interface{}{
+			"buildNum":               map[string]interface{}{"userValue": 73000},
+			"defaultRoute":           map[string]interface{}{"userValue": "/app/dashboards"},
+			"theme:darkMode":         map[string]interface{}{"userValue": true},
+			"timepicker:quickRanges": map[string]interface{}{"userValue": []interface{}{}},
+		},
+	}
+}
+
+func (m *mockKibana) findSavedObjects() interface{} {
+	return map[string]interface{}{
+		"page":          1,
+		"per_page":      1,
+		"total":         1,
+		"saved_objects": []map[string]interface{}{{"id": "8.15.0", "type": "config"}},
+	}
+}
+
+// The OpenSearch Dashboards plugin APIs answer list requests with a total
+
+func (m *mockKibana) alertingMonitors() interface{} {
+	return map[string]interface{}{"ok": true, "monitors": []interface{}{}, "totalMonitors": 12}
+}
+
+func (m *mockKibana) alertingAlerts() interface{} {
+	return map[string]interface{}{"ok": true, "alerts": []interface{}{}, "totalAlerts": 2}
+}
+
+func (m *mockKibana) anomalyDetectors() interface{} {
+	return map[string]interface{}{"ok": true, "response": map[string]interface{}{"detectorList": []interface{}{}, "totalDetectors": 3}}
+}
+
+func (m *mockKibana) ismPolicies() interface{} {
+	return map[string]interface{}{"ok": true, "response": map[string]interface{}{"policies": []interface{}{}, "totalPolicies": 4}}
+}
+
+func (m *mockKibana) ismManagedIndices() interface{} {
+	return map[string]interface{}{"ok": true, "response": map[string]interface{}{"managedIndices": []interface{}{}, "totalManagedIndices": 57}}
+}
+
+func (m *mockKibana) kibanaDeprecations() interface{} {
+	return map[string]interface{}{
+		"deprecations": []map[string]interface{}{
+			{"level": "warning", "domainId": "dashboard", "title": "Legacy URL format"},
+		},
+	}
+}
+
+func (m *mockKibana) esDeprecations() interface{} {
+	return map[string]interface{}{
+		"totalCriticalDeprecations": 1,
+		"deprecations": []map[string]interface{}{
+			{"level": "critical", "type": "index_settings", "message": "Old index"},
+			{"level": "warning", "type": "cluster_settings", "message": "Deprecated setting"},
+		},
+	}
+}
+
+func (m *mockKibana) upgradeStatus() interface{} {
+	return map[string]interface{}{"readyForUpgrade": false, "details": "1 critical deprecation"}
+}
+
+func (m *mockKibana) stats() interface{} {
+	return map[string]interface{}{
+		"kibana": map[string]interface{}{
+			"name": "mock-kibana", "uuid": "00000000-0000-0000-0000-000000000000",
+			"version": m.version, "status": m.level,
+		},
+		"cluster_uuid":                  "mock-cluster-uuid-0000000000",
+		"last_updated":                  time.Now().UTC().Format(time.RFC3339),
+		"collection_interval_in_millis": mockCollectionInterval.Milliseconds(),
+		"concurrent_connections":        rand.IntN(20),
+		"process": map[string]interface{}{
+			"memory": map[string]interface{}{
+				"heap": map[string]interface{}{"used_in_bytes": int64(m.heapUsed), "size_limit": int64(4 << 30)},
+			},
+			"event_loop_delay": 5 + rand.Float64()*20,
+			"uptime_in_millis": float64(time.Since(m.started).Milliseconds()),
+		},
+	}
+}
+
+func (m *mockKibana) taskManager() interface{} {
+	status := map[string]string{"available": "OK", "degraded": "warn", "unavailable": "error"}[m.level]
+	return map[string]interface{}{
+		"id":        "00000000-0000-0000-0000-000000000000",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"status":    status,
+		"stats": map[string]interface{}{
+			"runtime": map[string]interface{}{
+				"status": status,
+				"value": map[string]interface{}{
+					"drift": map[string]interface{}{"p50": 500 + rand.IntN(500), "p99": 2000 + rand.IntN(3000)},
+				},
+			},
+			"workload": map[string]interface{}{
+				"status": "OK",
+				"value": map[string]interface{}{
+					"count": 42,
+					"task_types": map[string]interface{}{
+						"alerting:.index-threshold": map[string]interface{}{"count": 12},
+						"actions:.email":            map[string]interface{}{"count": 30},
+					},
+				},
+			},
+		},
+	}
+}
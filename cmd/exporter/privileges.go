@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// kibanaRole is the body of Kibana's PUT /api/security/role/{name}
+type kibanaRole struct {
+	Elasticsearch struct {
+		Cluster []string      `json:"cluster"`
+		Indices []interface{} `json:"indices"`
+	} `json:"elasticsearch"`
+	Kibana []kibanaRolePrivileges `json:"kibana"`
+}
+
+type kibanaRolePrivileges struct {
+	Base    []string            `json:"base"`
+	Feature map[string][]string `json:"feature"`
+	Spaces  []string            `json:"spaces"`
+}
+
+// runPrintRequiredPrivileges prints the least-privilege Kibana role for the
+// given collectors. Status and the authentication check only need a user
+// that can authenticate, so they add nothing to the role.
+func runPrintRequiredPrivileges(args []string) int {
+	fs := flag.NewFlagSet("print-required-privileges", flag.ContinueOnError)
+	collectors := fs.String("collectors", collector.CollectorStatus, "Comma-separated enabled collectors: status, auth, spaces, custom, ui_settings, cluster_info, deprecations, saved_objects_probe, frontend, opensearch_plugins")
+	spacesCollectors := fs.String("spaces-collectors", "saved_objects,alerting_rules,data_views", "Comma-separated per-space collectors, as passed to the exporter")
+	roleName := fs.String("role-name", "kibana_exporter", "Role name used in the printed instructions")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	enabled := splitList(*collectors)
+	for _, name := range enabled {
+		if !collector.ValidCollector(name) {
+			fmt.Fprintf(os.Stderr, "unknown collector %q\n", name)
+			return 1
+		}
+	}
+
+	var role kibanaRole
+	role.Elasticsearch.Cluster = []string{}
+	if slices.Contains(enabled, collector.CollectorClusterInfo) {
+		// Kibana reads the cluster UUID from Elasticsearch with the user's credentials
+		role.Elasticsearch.Cluster = append(role.Elasticsearch.Cluster, "monitor")
+	}
+	role.Elasticsearch.Indices = []interface{}{}
+	role.Kibana = []kibanaRolePrivileges{}
+
+	if slices.Contains(enabled, collector.CollectorSpaces) {
+		features := make(map[string][]string)
+		for _, name := range splitList(*spacesCollectors) {
+			privilege, ok := collector.RequiredPrivilege(name)
+			if !ok || collector.ValidCollector(name) {
+				fmt.Fprintf(os.Stderr, "unknown per-space collector %q\n", name)
+				return 1
+			}
+			if !slices.Contains(features[privilege.Feature], privilege.Level) {
+				features[privilege.Feature] = append(features[privilege.Feature], privilege.Level)
+			}
+		}
+		// Spaces are only listed if the role has a privilege in them
+		role.Kibana = append(role.Kibana, kibanaRolePrivileges{Base: []string{}, Feature: features, Spaces: []string{"*"}})
+	}
+	// Advanced settings and the saved object probe are read from the default
+	// space only
+	for _, name := range []string{collector.CollectorUISettings, collector.CollectorSavedObjectsProbe} {
+		if !slices.Contains(enabled, name) {
+			continue
+		}
+		privilege, _ := collector.RequiredPrivilege(name)
+		if len(role.Kibana) > 0 {
+			features := role.Kibana[0].Feature
+			if !slices.Contains(features[privilege.Feature], privilege.Level) {
+				features[privilege.Feature] = append(features[privilege.Feature], privilege.Level)
+			}
+		} else {
+			role.Kibana = append(role.Kibana, kibanaRolePrivileges{
+				Base:    []string{},
+				Feature: map[string][]string{privilege.Feature: {privilege.Level}},
+				Spaces:  []string{"default"},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(role)
+
+	fmt.Fprintf(os.Stderr, "Create the role with PUT /api/security/role/%s and assign it to the scrape user.\n", *roleName)
+	if slices.Contains(enabled, collector.CollectorDeprecations) {
+		fmt.Fprintln(os.Stderr, "The deprecations collector needs the privileges of Kibana's Upgrade Assistant; add them to the role.")
+	}
+	if slices.Contains(enabled, collector.CollectorOpenSearchPlugins) {
+		fmt.Fprintln(os.Stderr, "The opensearch_plugins collector needs OpenSearch Security permissions for the plugins' APIs, which this role does not cover.")
+	}
+	if slices.Contains(enabled, collector.CollectorCustom) {
+		fmt.Fprintln(os.Stderr, "Custom metrics endpoints need the privileges of the APIs they call; add them to the role.")
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// registerProbe serves the metrics of one configured target at
+// /probe?target=<url>, so that a Prometheus job discovering the targets
+// through /sd scrapes each of them separately. Only configured targets can
+// be probed, as the exporter sends them its credentials. labels and
+// decorate are applied as on the metrics endpoint.
+func registerProbe(mux *http.ServeMux, targets *collector.Targets, labels prometheus.Labels, decorate func(prometheus.Gatherer) prometheus.Gatherer) {
+	mux.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		var registerer prometheus.Registerer = registry
+		if len(labels) > 0 {
+			registerer = prometheus.WrapRegistererWith(labels, registry)
+		}
+		found, err := targets.RegisterTarget(registerer, target)
+		if !found {
+			http.Error(w, "unknown target "+target, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(decorate(registry), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// reportRow is the health summary of one Kibana in a fleet report
+type reportRow struct {
+	URL           string            `json:"url"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Result        string            `json:"result"`
+	Level         string            `json:"level,omitempty"`
+	Version       string            `json:"version,omitempty"`
+	HeapPercent   float64           `json:"heap_percent,omitempty"`
+	UptimeSeconds float64           `json:"uptime_seconds,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	ErrorCode     string            `json:"error_code,omitempty"`
+	exitCode      int
+}
+
+// runReport scrapes every target of a targets file or URL list once and
+// prints a fleet health summary. The exit code is that of the worst target,
+// with the check subcommand's meanings.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	targetsFile := fs.String("targets-file", "", "JSON targets file, as used by the exporter")
+	kibanaURLs := fs.String("kibana-urls", "", "Comma-separated Kibana URLs, if no targets file is given")
+	credentials := registerCredentialFlags(fs)
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout for each Kibana request")
+	insecureSkipVerify := fs.Bool("insecure-skip-verify", false, "Skip TLS certificate verification")
+	schema := fs.String("kibana-schema", "auto", "Format of /api/status for targets without a schema: auto, kibana8, kibana7 or opensearch")
+	userAgent := fs.String("user-agent", defaultUserAgent(), "User-Agent header sent to Kibana")
+	concurrency := fs.Int("concurrency", 10, "Number of Kibanas scraped at once")
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return checkUsage
+	}
+	if !collector.ValidSchema(*schema) {
+		fmt.Fprintf(os.Stderr, "unknown schema %q\n", *schema)
+		return checkUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		return checkUsage
+	}
+	if *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "--concurrency must be at least 1")
+		return checkUsage
+	}
+
+	credentials.fromEnv()
+
+	var targets []collector.Target
+	var err error
+	switch {
+	case *targetsFile != "" && *kibanaURLs != "":
+		fmt.Fprintln(os.Stderr, "--targets-file and --kibana-urls are mutually exclusive")
+		return checkUsage
+	case *targetsFile != "":
+		targets, err = collector.LoadTargets(*targetsFile)
+	default:
+		targets, err = urlTargets(*kibanaURLs)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return checkUsage
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "no targets, set --targets-file or --kibana-urls")
+		return checkUsage
+	}
+	applyTargetCredentials(targets)
+
+	// Credentials set on a target win over the shared ones
+	base := collector.Config{
+		Timeout:            *timeout,
+		InsecureSkipVerify: *insecureSkipVerify,
+		UserAgent:          *userAgent,
+		Schema:             *schema,
+	}
+	if err := credentials.configure(&base); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return checkUsage
+	}
+
+	rows := make([]reportRow, len(targets))
+	slots := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		config := base
+		config.KibanaURL = target.URL
+		config.Transport = collector.TransportConfig{Proxy: target.Proxy}
+		if target.Username != "" {
+			config.Username, config.Password = target.Username, target.Password
+			config.UsernameFile, config.PasswordFile = "", ""
+			config.AuthProvider, config.AuthParams, config.AuthParamFiles = "", nil, nil
+		}
+		if target.Schema != "" {
+			config.Schema = target.Schema
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			rows[i] = reportTarget(config, target.Labels)
+		}()
+	}
+	wg.Wait()
+
+	exitCode := checkHealthy
+	for _, row := range rows {
+		if reportSeverity(row.exitCode) > reportSeverity(exitCode) {
+			exitCode = row.exitCode
+		}
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rows)
+	} else {
+		printReport(rows)
+	}
+	return exitCode
+}
+
+func reportTarget(config collector.Config, labels map[string]string) reportRow {
+	status, err := collector.NewKibanaCollector(config).Scrape(context.Background())
+	row := reportRow{URL: config.KibanaURL, Labels: labels}
+	switch {
+	case errors.Is(err, collector.ErrAuth):
+		row.Result, row.exitCode = "auth_failed", checkAuth
+	case err != nil:
+		row.Result, row.exitCode = "unreachable", checkUnreachable
+	case status.Status.Overall.Level != "available":
+		row.Result, row.exitCode = "degraded", checkDegraded
+	default:
+		row.Result, row.exitCode = "healthy", checkHealthy
+	}
+	if err != nil {
+		row.Error = err.Error()
+		row.ErrorCode = collector.ErrorCode(err)
+		return row
+	}
+
+	row.Level = status.Status.Overall.Level
+	row.Version = status.Version.Number
+	if mem := status.Metrics.Process.Memory; mem != nil && mem.Heap != nil && mem.Heap.SizeLimit > 0 {
+		row.HeapPercent = 100 * float64(mem.Heap.UsedBytes) / float64(mem.Heap.SizeLimit)
+	}
+	if uptime := status.Metrics.Process.Uptime; uptime != nil {
+		row.UptimeSeconds = *uptime / 1000
+	}
+	return row
+}
+
+// reportSeverity orders exit codes from healthy to unreachable
+func reportSeverity(code int) int {
+	switch code {
+	case checkDegraded:
+		return 1
+	case checkAuth:
+		return 2
+	case checkUnreachable:
+		return 3
+	}
+	return 0
+}
+
+func printReport(rows []reportRow) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tRESULT\tLEVEL\tVERSION\tHEAP\tUPTIME\tERROR")
+	for _, r := range rows {
+		heap, uptime := "-", "-"
+		if r.HeapPercent > 0 {
+			heap = fmt.Sprintf("%.0f%%", r.HeapPercent)
+		}
+		if r.UptimeSeconds > 0 {
+			uptime = time.Duration(r.UptimeSeconds * float64(time.Second)).Round(time.Second).String()
+		}
+		errText := "-"
+		if r.Error != "" {
+			errText = fmt.Sprintf("[%s] %s", r.ErrorCode, r.Error)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.URL, r.Result, orDash(r.Level), orDash(r.Version), heap, uptime, errText)
+	}
+	w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cgroupMemoryFiles holds the memory limit of the container for cgroup v2 and v1
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// autoMemoryLimitRatio leaves headroom for non-heap memory below the container limit
+const autoMemoryLimitRatio = 0.9
+
+// configureRuntime applies the memory limit and logs the effective runtime settings.
+// GOMAXPROCS already follows the cgroup CPU quota since Go 1.25.
+func configureRuntime(memoryLimit string) error {
+	if memoryLimit != "" {
+		limit, err := parseMemoryLimit(memoryLimit)
+		if err != nil {
+			return err
+		}
+		if limit > 0 {
+			debug.SetMemoryLimit(limit)
+		}
+	}
+
+	fields := log.Fields{"gomaxprocs": runtime.GOMAXPROCS(0)}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		fields["gomemlimit"] = limit
+	}
+	log.WithFields(fields).Info("Configured Go runtime")
+	return nil
+}
+
+// parseMemoryLimit parses a byte size with an optional B/KiB/MiB/GiB suffix,
+// or "auto" for a fraction of the container's cgroup memory limit
+func parseMemoryLimit(value string) (int64, error) {
+	if value == "auto" {
+		limit := cgroupMemoryLimit()
+		if limit <= 0 {
+			log.Warn("No container memory limit found, leaving the Go memory limit unset")
+			return 0, nil
+		}
+		return int64(float64(limit) * autoMemoryLimitRatio), nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{
+		{"GiB", 1 << 30},
+		{"MiB", 1 << 20},
+		{"KiB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory limit %q: expected bytes with an optional B/KiB/MiB/GiB suffix, or auto", value)
+	}
+	return n * multiplier, nil
+}
+
+// cgroupMemoryLimit returns the container memory limit in bytes, or 0 if unlimited or unknown
+func cgroupMemoryLimit() int64 {
+	for _, path := range cgroupMemoryFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		// cgroup v1 reports a huge page-aligned number when unlimited
+		if err != nil || limit >= 1<<62 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
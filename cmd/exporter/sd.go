@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// sdGroup is a target group in Prometheus HTTP SD format
+type sdGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// registerServiceDiscovery serves the current targets at /sd in Prometheus
+// HTTP SD format, one group per Kibana instance carrying its labels
+func registerServiceDiscovery(mux *http.ServeMux, targets *collector.Targets) {
+	mux.HandleFunc("/sd", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sdGroups(targets.List()))
+	})
+}
+
+// sdGroups returns one target group per Kibana instance. The host is the
+// target, with the scheme and full URL (without credentials) as labels.
+func sdGroups(targets []collector.Target) []sdGroup {
+	groups := []sdGroup{}
+	for _, target := range targets {
+		u, err := url.Parse(target.URL)
+		if err != nil || u.Host == "" {
+			log.WithField("kibana_url", target.URL).Debug("Skipping target without a host in /sd")
+			continue
+		}
+		u.User = nil
+		labels := map[string]string{
+			"__scheme__":        u.Scheme,
+			"__meta_kibana_url": u.String(),
+		}
+		for name, value := range target.Labels {
+			labels[name] = value
+		}
+		groups = append(groups, sdGroup{Targets: []string{u.Host}, Labels: labels})
+	}
+	return groups
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+// applyCollectorSettings lets the collectors block of the targets file
+// enable or disable collectors, overriding their flags
+func applyCollectorSettings(settings map[string]collector.CollectorSettings, authCheck, spacesMode, clusterInfo, deprecations *bool, savedObjectsProbe, frontendProbe, openSearchPlugins, uiSettings, customMetricsFile *string) {
+	for name, s := range settings {
+		if s.Enabled == nil {
+			continue
+		}
+		switch name {
+		case collector.CollectorAuth:
+			*authCheck = *s.Enabled
+		case collector.CollectorSpaces:
+			*spacesMode = *s.Enabled
+		case collector.CollectorClusterInfo:
+			*clusterInfo = *s.Enabled
+		case collector.CollectorDeprecations:
+			*deprecations = *s.Enabled
+		case collector.CollectorSavedObjectsProbe:
+			if !*s.Enabled {
+				*savedObjectsProbe = ""
+			} else if *savedObjectsProbe == "" {
+				log.Fatal("The saved_objects_probe collector is enabled in the targets file but --saved-objects-probe is not set")
+			}
+		case collector.CollectorFrontend:
+			if !*s.Enabled {
+				*frontendProbe = ""
+			} else if *frontendProbe == "" {
+				log.Fatal("The frontend collector is enabled in the targets file but --frontend-probe is not set")
+			}
+		case collector.CollectorOpenSearchPlugins:
+			if !*s.Enabled {
+				*openSearchPlugins = ""
+			} else if *openSearchPlugins == "" {
+				log.Fatal("The opensearch_plugins collector is enabled in the targets file but --opensearch-plugins is not set")
+			}
+		case collector.CollectorUISettings:
+			if !*s.Enabled {
+				*uiSettings = ""
+			} else if *uiSettings == "" {
+				log.Fatal("The ui_settings collector is enabled in the targets file but --ui-settings is not set")
+			}
+		case collector.CollectorCustom:
+			if !*s.Enabled {
+				*customMetricsFile = ""
+			} else if *customMetricsFile == "" {
+				log.Fatal("The custom collector is enabled in the targets file but --custom-metrics-file is not set")
+			}
+		}
+	}
+}
+
+// collectorTimeouts returns the time budget of every collector that has one
+func collectorTimeouts(settings map[string]collector.CollectorSettings) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration)
+	for name, s := range settings {
+		if s.Timeout > 0 {
+			timeouts[name] = s.Timeout
+		}
+	}
+	return timeouts
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// warmUp scrapes every target once at startup, after a random delay of up to
+// the jitter, so that the first Prometheus scrape after a deploy finds
+// connections open and rates primed. Replicas rolled out together spread
+// their warm-up scrapes over the jitter instead of hitting Kibana at once.
+type warmUp struct {
+	done atomic.Bool
+}
+
+func startWarmUp(gatherer prometheus.Gatherer, jitter time.Duration) *warmUp {
+	w := &warmUp{}
+	go func() {
+		if jitter > 0 {
+			time.Sleep(rand.N(jitter))
+		}
+		start := time.Now()
+		if _, err := gatherer.Gather(); err != nil {
+			log.WithError(err).Warn("Warm-up scrape failed")
+		}
+		log.WithField("duration", time.Since(start).String()).Info("Warm-up scrape done")
+		w.done.Store(true)
+	}()
+	return w
+}
+
+// ready reports whether the warm-up scrape is done; a nil warmUp is always ready
+func (w *warmUp) ready() bool {
+	return w == nil || w.done.Load()
+}
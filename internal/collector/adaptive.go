@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// minAdaptiveSamples is the number of scrapes a target needs before its
+// timeout adapts; until then the maximum applies
+const minAdaptiveSamples = 10
+
+// AdaptiveTimeoutConfig derives the timeout of a target's /api/status
+// requests from the latency of its recent successful scrapes, so slow but
+// healthy instances get more time and fast ones fail fast
+type AdaptiveTimeoutConfig struct {
+	Enabled bool
+	// Percentile of the recent scrape durations, e.g. 0.99
+	Percentile float64
+	// Multiplier is the headroom over the percentile, e.g. 3
+	Multiplier float64
+	// Min bounds the timeout from below; Config.Timeout bounds it from above
+	Min time.Duration
+}
+
+// Validate checks the percentile, multiplier and minimum against the
+// maximum timeout
+func (a AdaptiveTimeoutConfig) Validate(timeout time.Duration) error {
+	if !a.Enabled {
+		return nil
+	}
+	if a.Percentile <= 0 || a.Percentile > 1 {
+		return fmt.Errorf("percentile %v out of range (0, 1]", a.Percentile)
+	}
+	if a.Multiplier < 1 {
+		return fmt.Errorf("multiplier %v is below 1", a.Multiplier)
+	}
+	if a.Min <= 0 {
+		return fmt.Errorf("minimum timeout must be positive")
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("the timeout must be positive to bound the adaptive timeout")
+	}
+	if a.Min > timeout {
+		return fmt.Errorf("minimum timeout %s exceeds the timeout %s", a.Min, timeout)
+	}
+	return nil
+}
+
+// adaptiveTimeout computes a target's timeout from its scrape history
+type adaptiveTimeout struct {
+	config AdaptiveTimeoutConfig
+	max    time.Duration
+}
+
+func newAdaptiveTimeout(config AdaptiveTimeoutConfig, timeout time.Duration) *adaptiveTimeout {
+	return &adaptiveTimeout{config: config, max: timeout}
+}
+
+// timeout returns the percentile of the recent scrape durations times the
+// multiplier, bounded by the minimum and maximum. Scrapes that timed out
+// count with the time they took, so a target that slows down for good
+// raises its timeout instead of failing until the history rolls over.
+func (a *adaptiveTimeout) timeout(samples []Sample) time.Duration {
+	durations := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		if s.Up || s.Error == CodeTimeout {
+			durations = append(durations, s.Duration)
+		}
+	}
+	if len(durations) < minAdaptiveSamples {
+		return a.max
+	}
+	slices.Sort(durations)
+	index := int(math.Ceil(a.config.Percentile*float64(len(durations)))) - 1
+	timeout := time.Duration(durations[max(index, 0)] * a.config.Multiplier * float64(time.Second))
+	return min(max(timeout, a.config.Min), a.max)
+}
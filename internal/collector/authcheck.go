@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// securityUser is the relevant part of /internal/security/me
+type securityUser struct {
+	Username               string `json:"username"`
+	AuthenticationProvider struct {
+		Type string `json:"type"`
+	} `json:"authentication_provider"`
+}
+
+func newAuthOKDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "auth_ok"),
+		"Whether the configured credentials authenticate as the configured user",
+		nil, nil,
+	)
+}
+
+// collectAuth verifies that the credentials authenticate, which /api/status
+// does not prove when it allows anonymous access. Nothing is exported when
+// the check is inconclusive, e.g. with security disabled.
+func (c *KibanaCollector) collectAuth(ctx context.Context, ch chan<- prometheus.Metric) {
+	ok, err := c.checkAuth(ctx)
+	if err != nil && !errors.Is(err, ErrAuth) {
+		log.WithError(err).WithField("target", c.config.KibanaURL).Debug("Authentication check inconclusive")
+		return
+	}
+	value := 0.0
+	if ok {
+		value = 1
+	} else {
+		log.WithError(err).WithFields(log.Fields{
+			"target":   c.config.KibanaURL,
+			"username": c.auth.Load().username,
+		}).Warn("Kibana credentials do not authenticate")
+	}
+	ch <- prometheus.MustNewConstMetric(c.authOK, prometheus.GaugeValue, value)
+}
+
+func (c *KibanaCollector) checkAuth(ctx context.Context) (bool, error) {
+	var user securityUser
+	if err := c.getJSON(ctx, "/internal/security/me", &user); err != nil {
+		return false, err
+	}
+	// Anonymous access answers for any request without valid credentials
+	username := c.auth.Load().username
+	if user.AuthenticationProvider.Type == "anonymous" || user.Username != username {
+		return false, newScrapeError(ErrAuth, "authenticated as %q via %s instead of %q",
+			user.Username, user.AuthenticationProvider.Type, username)
+	}
+	return true, nil
+}
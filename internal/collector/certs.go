@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type certDescs struct {
+	expiry        *prometheus.Desc
+	daysRemaining *prometheus.Desc
+}
+
+func newCertDescs() certDescs {
+	return certDescs{
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tls", "cert_expiry_timestamp_seconds"),
+			"Expiry time of each certificate presented by Kibana",
+			[]string{"subject_cn", "issuer_cn", "serial"}, nil,
+		),
+		daysRemaining: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tls", "cert_days_remaining"),
+			"Days until the first certificate presented by Kibana expires",
+			nil, nil,
+		),
+	}
+}
+
+func (d certDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.expiry
+	ch <- d.daysRemaining
+}
+
+// exportCerts exports the expiry of the certificate chain seen on the last
+// TLS connection. Nothing is exported for plain HTTP targets.
+func (d certDescs) export(ch chan<- prometheus.Metric, certs []*x509.Certificate, now time.Time) {
+	if len(certs) == 0 {
+		return
+	}
+	earliest := time.Time{}
+	for _, cert := range certs {
+		ch <- prometheus.MustNewConstMetric(d.expiry, prometheus.GaugeValue, float64(cert.NotAfter.Unix()),
+			cert.Subject.CommonName, cert.Issuer.CommonName, cert.SerialNumber.Text(16))
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(d.daysRemaining, prometheus.GaugeValue, earliest.Sub(now).Hours()/24)
+}
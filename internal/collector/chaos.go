@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChaosConfig injects latency and failures into replayed Kibana responses,
+// to test alert rules and dashboards against a misbehaving Kibana without
+// touching a real one
+type ChaosConfig struct {
+	// Latency delays every response by up to this duration
+	Latency time.Duration
+	// FailureRate is the fraction of requests answered with a 503
+	FailureRate float64
+}
+
+func (c ChaosConfig) enabled() bool {
+	return c.Latency > 0 || c.FailureRate > 0
+}
+
+type chaosTransport struct {
+	next   http.RoundTripper
+	config ChaosConfig
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.config.Latency > 0 {
+		timer := time.NewTimer(rand.N(t.config.Latency))
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	if rand.Float64() < t.config.FailureRate {
+		return &http.Response{
+			Status:     "503 Service Unavailable",
+			StatusCode: http.StatusServiceUnavailable,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("simulated failure")),
+			Request:    req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
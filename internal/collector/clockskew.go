@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultCollectionInterval is Kibana's ops.interval, used when the status
+// does not report its collection interval
+const defaultCollectionInterval = 5 * time.Second
+
+func newClockSkewDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "clock_skew_seconds"),
+		"How far Kibana's clock is ahead (positive) or behind (negative) the exporter's, derived from collected_at; 0 within the measurement's uncertainty",
+		nil, nil,
+	)
+}
+
+// clockSkew estimates the offset of Kibana's clock from a status requested
+// at sent and received at received. Kibana collects its metrics every
+// interval, so collected_at lies between sent minus the interval and
+// received on a correct clock; only the distance outside that range is
+// skew, which keeps network latency and collection lag from showing up as
+// drift.
+func clockSkew(collectedAt, sent, received time.Time, interval time.Duration) time.Duration {
+	switch earliest := sent.Add(-interval); {
+	case collectedAt.After(received):
+		return collectedAt.Sub(received)
+	case collectedAt.Before(earliest):
+		return collectedAt.Sub(earliest)
+	}
+	return 0
+}
+
+// exportClockSkew exports the clock skew of a status requested at sent and
+// received at received, if it has a collected_at
+func (c *KibanaCollector) exportClockSkew(ch chan<- prometheus.Metric, status *KibanaStatus, sent, received time.Time) {
+	collectedAt, err := time.Parse(time.RFC3339, status.Metrics.CollectedAt)
+	if err != nil {
+		return
+	}
+	interval := defaultCollectionInterval
+	if ms := status.Metrics.CollectionInterval; ms != nil && *ms > 0 {
+		interval = time.Duration(*ms * float64(time.Millisecond))
+	}
+	// collected_at may be truncated to whole seconds, up to a second early
+	if collectedAt.Nanosecond() == 0 {
+		interval += time.Second
+	}
+	skew := clockSkew(collectedAt, sent, received, interval)
+	ch <- prometheus.MustNewConstMetric(c.clockSkew, prometheus.GaugeValue, skew.Seconds())
+}
@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// classifyCloudProxy returns a connection error for responses that the
+// Elastic Cloud proxy sent itself because the deployment's Kibana was
+// unreachable, e.g. a 404 "Unknown resource." while it is being restarted,
+// or nil for responses that came from Kibana. Proxied responses carry the
+// X-Found-Handling-Cluster header.
+func classifyCloudProxy(resp *http.Response, body string) error {
+	if resp.Header.Get("X-Found-Handling-Cluster") != "" {
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &ScrapeError{
+			Kind:       ErrConnection,
+			Err:        fmt.Errorf("elastic cloud proxy returned status %d without reaching kibana: %s", resp.StatusCode, body),
+			StatusCode: resp.StatusCode,
+			Body:       body,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// clusterInfoEndpoint includes the Elasticsearch cluster UUID only when extended
+const clusterInfoEndpoint = "/api/stats?extended=true"
+
+// kibanaStats is the relevant part of /api/stats?extended=true
+type kibanaStats struct {
+	ClusterUUID string `json:"cluster_uuid"`
+}
+
+func newClusterInfoDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "elasticsearch", "cluster_info"),
+		"Elasticsearch cluster Kibana is connected to, for joins with Elasticsearch metrics; always 1",
+		[]string{"cluster_uuid"}, nil,
+	)
+}
+
+// collectClusterInfo exports the UUID of the Elasticsearch cluster behind
+// Kibana. Nothing is exported while Kibana cannot tell, e.g. when
+// Elasticsearch is unreachable.
+func (c *KibanaCollector) collectClusterInfo(ctx context.Context, ch chan<- prometheus.Metric) {
+	if c.forbidden.skip(CollectorClusterInfo, clusterInfoEndpoint) {
+		return
+	}
+	var stats kibanaStats
+	if err := c.getJSON(ctx, clusterInfoEndpoint, &stats); err != nil {
+		if !c.forbidden.observe(c.config.KibanaURL, CollectorClusterInfo, clusterInfoEndpoint, err) {
+			log.WithError(err).WithFields(log.Fields{
+				"target":     c.config.KibanaURL,
+				"error_code": ErrorCode(err),
+			}).Warn("Failed to scrape Elasticsearch cluster info")
+		}
+		return
+	}
+	if stats.ClusterUUID == "" {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.clusterInfo, prometheus.GaugeValue, 1, stats.ClusterUUID)
+}
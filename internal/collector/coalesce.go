@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// coalescer lets scrapes of the same collectors that arrive within a short
+// window of each other share one Kibana fetch: the first scrape records what
+// the collectors sent and the others replay it. HA Prometheus pairs scrape
+// every target twice per interval, a few hundred milliseconds apart. Guarded
+// by the collector mutex.
+type coalescer struct {
+	window  time.Duration
+	fetches map[string]coalescedFetch
+	shared  uint64
+	desc    *prometheus.Desc
+}
+
+type coalescedFetch struct {
+	start   time.Time
+	metrics []prometheus.Metric
+}
+
+func newCoalescer(window time.Duration) coalescer {
+	return coalescer{
+		window:  window,
+		fetches: make(map[string]coalescedFetch),
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "coalesced_scrapes_total"),
+			"Scrapes answered from a Kibana fetch of another scrape within --coalesce-window",
+			nil, nil,
+		),
+	}
+}
+
+// selectionKey identifies the collectors a scrape runs
+func selectionKey(selected selection) string {
+	if selected == nil {
+		return "*"
+	}
+	return strings.Join(slices.Sorted(maps.Keys(selected)), ",")
+}
+
+// run sends the metrics of a fetch of the same collectors that started within
+// the window before now, or runs fetch and records what it sends
+func (c *coalescer) run(ch chan<- prometheus.Metric, selected selection, now time.Time, fetch func(chan<- prometheus.Metric)) {
+	if c.window <= 0 {
+		fetch(ch)
+		return
+	}
+	key := selectionKey(selected)
+	if previous, ok := c.fetches[key]; ok && now.Sub(previous.start) < c.window {
+		for _, m := range previous.metrics {
+			ch <- m
+		}
+		c.shared++
+		return
+	}
+	for k, previous := range c.fetches {
+		if now.Sub(previous.start) >= c.window {
+			delete(c.fetches, k)
+		}
+	}
+
+	record := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var metrics []prometheus.Metric
+		for m := range record {
+			ch <- m
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+	fetch(record)
+	close(record)
+	c.fetches[key] = coalescedFetch{start: now, metrics: <-done}
+}
+
+func (c *coalescer) describe(ch chan<- *prometheus.Desc) {
+	if c.window > 0 {
+		ch <- c.desc
+	}
+}
+
+func (c *coalescer) export(ch chan<- prometheus.Metric) {
+	if c.window > 0 {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(c.shared))
+	}
+}
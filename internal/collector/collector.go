@@ -1,16 +1,23 @@
 package collector
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/pkg/kibana"
 )
 
 const namespace = "kibana"
@@ -22,13 +29,179 @@ type Config struct {
 	Password           string
 	Timeout            time.Duration
 	InsecureSkipVerify bool
+	Redirects          RedirectPolicy
+	// XSRFValue is sent as the kbn-xsrf header ("true" if empty)
+	XSRFValue string
+	// APIVersion is sent as the elastic-api-version header when set
+	APIVersion string
+	// InternalOrigin marks requests as internal with x-elastic-internal-origin,
+	// which Kibana 9 requires for internal APIs
+	InternalOrigin bool
+	// UserAgent identifies the exporter in Kibana and proxy access logs
+	UserAgent string
+	Transport TransportConfig
+	Tracer    Tracer
+	// Sidecar tunes the collector for running next to Kibana in the same pod
+	Sidecar bool
+	// Schema pins the /api/status format (see SchemaAuto and friends)
+	Schema string
+	// AuthCheck verifies on every scrape that the credentials authenticate
+	AuthCheck bool
+	// Spaces enables per-space metrics
+	Spaces SpacesConfig
+	// CollectorTimeouts bound the time each named collector may spend per scrape
+	CollectorTimeouts map[string]time.Duration
+	// CoalesceWindow lets scrapes of the same collectors within this long of
+	// each other share one Kibana fetch (0 disables coalescing)
+	CoalesceWindow time.Duration
+	// WorkerPools bound how many targets run each collector at once; shared
+	// by all targets (nil runs collectors unbounded)
+	WorkerPools *WorkerPools
+	// ForbiddenCooldown is how long an optional collector endpoint is skipped
+	// after a 403 (DefaultForbiddenCooldown if zero)
+	ForbiddenCooldown time.Duration
+	// OnStateChange is called when a target goes up or down or changes its
+	// overall status level; it must not block
+	OnStateChange func(StateChange)
+	// PluginStatus enables per-plugin status metrics
+	PluginStatus PluginStatusConfig
+	// ResponseTime selects the exported response time statistics
+	ResponseTime ResponseTimeConfig
+	// CaptureHeaders are response headers of /api/status exported as info
+	// metrics and CaptureHeaderGauges those exported as numeric gauges
+	CaptureHeaders      []string
+	CaptureHeaderGauges []string
+	// UISettings are the keys of the advanced settings to export; empty
+	// disables the collector
+	UISettings []string
+	// ClusterInfo exports the UUID of the Elasticsearch cluster behind Kibana
+	ClusterInfo bool
+	// Deprecations exports Upgrade Assistant deprecation counts and readiness
+	Deprecations bool
+	// SavedObjectsProbe is the saved object type a synthetic search looks
+	// for on every scrape; empty disables the probe
+	SavedObjectsProbe string
+	// FrontendProbe is the path of a page requested without credentials on
+	// every scrape, e.g. /login; empty disables the probe. The page must be
+	// served with FrontendProbeStatus (200 if zero) and contain
+	// FrontendProbeContains, if set.
+	FrontendProbe         string
+	FrontendProbeStatus   int
+	FrontendProbeContains string
+	// OpenSearchPlugins are the OpenSearch Dashboards plugins whose object
+	// counts are exported, e.g. alerting
+	OpenSearchPlugins []string
+	// CustomEndpoints are additional API paths mapped to metrics with JSONPath
+	CustomEndpoints []CustomEndpoint
+	// SlowScrapeThreshold logs a warning with a timing breakdown for scrapes
+	// taking longer than this (0 disables the check)
+	SlowScrapeThreshold time.Duration
+	// AdaptiveTimeout derives the /api/status timeout of each target from its
+	// recent latency instead of using Timeout
+	AdaptiveTimeout AdaptiveTimeoutConfig
+	// WatchdogTimeout cancels scrapes running longer than this and drops the
+	// connections to Kibana (0 disables the watchdog)
+	WatchdogTimeout time.Duration
+	// HeapPressureThreshold is the heap used / size limit ratio at or above
+	// which kibana_heap_pressure is 1
+	HeapPressureThreshold float64
+	// NativeHistograms exports the exporter's histograms with native buckets
+	// in addition to the classic ones
+	NativeHistograms bool
+	// ElasticCloud tells responses of the Elastic Cloud proxy apart from
+	// those of Kibana
+	ElasticCloud bool
+	// AuthProvider names the registered kibana.AuthProvider that adds
+	// credentials to requests (kibana.AuthBasic if empty); AuthParams are
+	// its provider-specific settings
+	AuthProvider string
+	AuthParams   map[string]string
+	// UsernameFile, PasswordFile and AuthParamFiles, e.g. {"api_key": file},
+	// replace the credentials of the same name with the trimmed content of
+	// the files, read at every scrape
+	UsernameFile   string
+	PasswordFile   string
+	AuthParamFiles map[string]string
+	// MemoryTrendWindow is the history the heap and RSS growth rates are
+	// fitted over (0 disables them)
+	MemoryTrendWindow time.Duration
+	// Downtime are recurring windows in which the target is not scraped
+	Downtime []DowntimeWindow
+	// paused is shared by all collectors of a Targets set
+	paused *atomic.Bool
+	// HistorySize is the number of scrapes kept for /history and the live
+	// dashboard (DefaultHistorySize if zero)
+	HistorySize int
+	// FailureLogInterval limits repeated scrape failure logs to one per interval (0 logs every failure)
+	FailureLogInterval time.Duration
 }
 
 // KibanaCollector collects metrics from Kibana
 type KibanaCollector struct {
-	config Config
-	client *http.Client
-	mutex  sync.Mutex
+	config            Config
+	client            *http.Client
+	resolver          *dnsResolver
+	mutex             sync.Mutex
+	failureLog        *failureLogSampler
+	tracker           scrapeTracker
+	probe             healthProbe
+	throttle          throttle
+	history           *history
+	custom            []customEndpoint
+	spaces            spacesDescs
+	uiSettings        uiSettingsDescs
+	deprecations      deprecationsDescs
+	savedObjectsProbe savedObjectsProbeDescs
+	savedObjects      savedObjectsStatusDescs
+	frontendProbe     frontendProbeDescs
+	openSearch        openSearchDescs
+	// auth holds the current authState. It is replaced when credentials
+	// change, and read without the collector mutex by /ready;
+	// authMutex serializes replacing it.
+	auth      atomic.Pointer[authState]
+	authMutex sync.Mutex
+	// secrets is set when credentials are read from files, guarded by authMutex
+	secrets *secretFiles
+
+	// Response sizes of the last scrape, guarded by mutex
+	payloadWire    int64
+	payloadDecoded int64
+	// peerCerts is the certificate chain of the last TLS connection, guarded by mutex
+	peerCerts []*x509.Certificate
+	certs     certDescs
+	// requestRate is fed by every successful status scrape, guarded by mutex
+	requestRate requestRate
+	transitions statusTransitions
+	restarts    restartDetector
+	forbidden   forbiddenEndpoints
+	deadlines   scrapeDeadlines
+	series      seriesCounts
+	downtime    downtime
+	disconnects disconnectCounter
+	coalesce    coalescer
+	shape       payloadShape
+	responses   *responseCounter
+	// memTrend is set when Config.MemoryTrendWindow is
+	memTrend *memoryTrend
+	// state is StateDown or the overall level of the last scrape
+	state string
+	// plugins is set when Config.PluginStatus is enabled
+	plugins *pluginStatus
+	// headers is set when Config.CaptureHeaders or CaptureHeaderGauges are
+	headers *capturedHeaders
+	// phases of the last status request, guarded by mutex
+	phases     scrapePhases
+	phaseDescs phaseDescs
+	// collectDuration observes whole Collect calls, Kibana requests included
+	collectDuration prometheus.Histogram
+	// slow is set when Config.SlowScrapeThreshold is
+	slow *slowScrapes
+	// watchdog is set when Config.WatchdogTimeout is and drops the
+	// connections of transport when it cancels a scrape
+	watchdog  *watchdog
+	transport *http.Transport
+	// adaptive is set when Config.AdaptiveTimeout is enabled
+	adaptive *adaptiveTimeout
 
 	// Metrics
 	up                 *prometheus.Desc
@@ -41,44 +214,62 @@ type KibanaCollector struct {
 	heapTotal      *prometheus.Desc
 	heapUsed       *prometheus.Desc
 	heapSizeLimit  *prometheus.Desc
+	heapRatio      *prometheus.Desc
+	heapPressure   *prometheus.Desc
 	residentSet    *prometheus.Desc
 	eventLoop      *prometheus.Desc
 	requestsTotal  *prometheus.Desc
-	responseTime   *prometheus.Desc
+	requestsRate   *prometheus.Desc
+	responseTime   responseTimeDescs
 	concurrentConn *prometheus.Desc
 
 	// Process metrics
-	uptime           *prometheus.Desc
-	processMemory    *prometheus.Desc
-	osCPUPercent     *prometheus.Desc
-	osLoadAvg1m      *prometheus.Desc
-	osLoadAvg5m      *prometheus.Desc
-	osLoadAvg15m     *prometheus.Desc
-	osMemTotal       *prometheus.Desc
-	osMemFree        *prometheus.Desc
-	osMemUsed        *prometheus.Desc
+	uptime        *prometheus.Desc
+	processMemory *prometheus.Desc
+	osCPUPercent  *prometheus.Desc
+	osLoadAvg1m   *prometheus.Desc
+	osLoadAvg5m   *prometheus.Desc
+	osLoadAvg15m  *prometheus.Desc
+	osMemTotal    *prometheus.Desc
+	osMemFree     *prometheus.Desc
+	osMemUsed     *prometheus.Desc
 
 	// Scrape metrics
 	scrapeDuration *prometheus.Desc
 	scrapeSuccess  *prometheus.Desc
+	lastErrorTime  *prometheus.Desc
+	startupPhase   *prometheus.Desc
+	payloadBytes   *prometheus.Desc
+	dnsChanges     *prometheus.Desc
+	dnsFailures    *prometheus.Desc
+	throttled      *prometheus.Desc
+	customSuccess  *prometheus.Desc
+	authOK         *prometheus.Desc
+	clusterInfo    *prometheus.Desc
+	clockSkew      *prometheus.Desc
 }
 
 // NewKibanaCollector creates a new collector
 func NewKibanaCollector(config Config) *KibanaCollector {
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: config.InsecureSkipVerify,
-		},
+	if config.Tracer == nil {
+		config.Tracer = noopTracer{}
 	}
+	transport, resolver := newTransport(config)
+	responses := newResponseCounter(wrapDebug(wrapRecording(transport, config.Transport), config.Transport.Debug))
 
 	client := &http.Client{
 		Timeout:   config.Timeout,
-		Transport: transport,
+		Transport: responses,
 	}
 
-	return &KibanaCollector{
-		config: config,
-		client: client,
+	c := &KibanaCollector{
+		config:     config,
+		client:     client,
+		resolver:   resolver,
+		transport:  transport,
+		responses:  responses,
+		failureLog: newFailureLogSampler(config.KibanaURL, config.FailureLogInterval),
+		history:    newHistory(config.HistorySize),
 
 		up: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "up"),
@@ -122,6 +313,16 @@ func NewKibanaCollector(config Config) *KibanaCollector {
 			"Heap size limit in bytes",
 			nil, nil,
 		),
+		heapRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "heap", "utilization_ratio"),
+			"Used heap divided by the heap size limit",
+			nil, nil,
+		),
+		heapPressure: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "heap", "pressure"),
+			"Whether heap utilization is at or above --heap-pressure-threshold, risking an out-of-memory crash",
+			nil, nil,
+		),
 		residentSet: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "memory", "resident_set_bytes"),
 			"Resident set size in bytes",
@@ -139,11 +340,12 @@ func NewKibanaCollector(config Config) *KibanaCollector {
 			"Total number of requests",
 			[]string{"status"}, nil,
 		),
-		responseTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "response_time", "seconds"),
-			"Response time statistics",
-			[]string{"quantile"}, nil,
+		requestsRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "requests", "per_second"),
+			"Requests per second over Kibana's collection intervals, smoothed with a moving average",
+			nil, nil,
 		),
+		responseTime: newResponseTimeDescs(config.ResponseTime),
 		concurrentConn: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "concurrent_connections", "total"),
 			"Number of concurrent connections",
@@ -210,7 +412,245 @@ func NewKibanaCollector(config Config) *KibanaCollector {
 			"Was the last scrape successful",
 			nil, nil,
 		),
+		startupPhase: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "startup", "phase"),
+			"Kibana startup phase derived from status responses in sidecar mode (1 for the current phase)",
+			[]string{"phase"}, nil,
+		),
+		payloadBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "payload_bytes"),
+			"Size of the last Kibana status response as received on the wire and after decompression",
+			[]string{"encoding"}, nil,
+		),
+		dnsChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "dns_resolution_changes_total"),
+			"Number of times the Kibana host resolved to a different set of addresses",
+			nil, nil,
+		),
+		dnsFailures: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "dns_resolution_failures_total"),
+			"Number of failed lookups of the Kibana host",
+			nil, nil,
+		),
+		throttled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "throttled_total"),
+			"Number of HTTP 429 responses received from Kibana or a fronting proxy",
+			nil, nil,
+		),
+		lastErrorTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "last_error_timestamp_seconds"),
+			"Unix timestamp of the last failed scrape (0 if none)",
+			nil, nil,
+		),
+	}
+	client.CheckRedirect = c.checkRedirect
+	c.certs = newCertDescs()
+	c.phaseDescs = newPhaseDescs()
+	c.transitions = newStatusTransitions()
+	c.savedObjects = newSavedObjectsStatusDescs()
+	c.restarts = newRestartDetector()
+	c.forbidden = newForbiddenEndpoints(config.ForbiddenCooldown)
+	c.deadlines = newScrapeDeadlines(config.Timeout, config.CollectorTimeouts)
+	c.series = newSeriesCounts()
+	c.downtime = newDowntime(config.Downtime)
+	c.disconnects = newDisconnectCounter()
+	c.coalesce = newCoalescer(config.CoalesceWindow)
+	c.shape = newPayloadShape()
+	c.clockSkew = newClockSkewDesc()
+	if config.MemoryTrendWindow > 0 {
+		c.memTrend = newMemoryTrend(config.MemoryTrendWindow)
+	}
+	if config.PluginStatus.Enabled {
+		c.plugins = newPluginStatus(config.PluginStatus)
+	}
+	if len(config.CaptureHeaders) > 0 || len(config.CaptureHeaderGauges) > 0 {
+		c.headers = newCapturedHeaders(config.CaptureHeaders, config.CaptureHeaderGauges)
+	}
+	c.collectDuration = newCollectDuration(config.NativeHistograms)
+	if config.SlowScrapeThreshold > 0 {
+		c.slow = newSlowScrapes(config.SlowScrapeThreshold)
+	}
+	if config.WatchdogTimeout > 0 {
+		c.watchdog = newWatchdog(config.WatchdogTimeout)
+	}
+	if config.AdaptiveTimeout.Enabled {
+		c.adaptive = newAdaptiveTimeout(config.AdaptiveTimeout, c.deadlines.budgets[CollectorStatus])
+	}
+	if c.authCheck() {
+		c.authOK = newAuthOKDesc()
+	}
+	if config.Spaces.Enabled {
+		c.spaces = newSpacesDescs()
+	}
+	if len(config.UISettings) > 0 {
+		c.uiSettings = newUISettingsDescs()
+	}
+	if config.ClusterInfo {
+		c.clusterInfo = newClusterInfoDesc()
 	}
+	if config.Deprecations {
+		c.deprecations = newDeprecationsDescs()
+	}
+	if config.SavedObjectsProbe != "" {
+		c.savedObjectsProbe = newSavedObjectsProbeDescs()
+	}
+	if config.FrontendProbe != "" {
+		c.frontendProbe = newFrontendProbeDescs()
+	}
+	if len(config.OpenSearchPlugins) > 0 {
+		c.openSearch = newOpenSearchDescs()
+	}
+	if len(config.CustomEndpoints) > 0 {
+		custom, err := compileCustomEndpoints(config.CustomEndpoints)
+		if err != nil {
+			log.WithError(err).Error("Invalid custom endpoints, ignoring them")
+		}
+		c.custom = custom
+		c.customSuccess = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "custom_endpoint_success"),
+			"Whether the last scrape of a custom endpoint succeeded",
+			[]string{"endpoint"}, nil,
+		)
+	}
+	c.secrets = newSecretFiles(config)
+	if c.secrets == nil {
+		c.setAuth(c.authConfig())
+	} else if err := c.reloadSecrets(); err != nil {
+		c.auth.Store(&authState{err: err, statusRequestErr: err})
+	}
+	return c
+}
+
+// authState is the auth provider of the current credentials and the status
+// request prepared with it. It is never modified, only replaced.
+type authState struct {
+	provider kibana.AuthProvider
+	// err is set when the provider could not be created
+	err error
+	// username is the basic auth username, verified by the auth check
+	username string
+	// statusRequest is built once and reused by every scrape
+	statusRequest    *http.Request
+	statusRequestErr error
+}
+
+// authConfig returns the configured credentials
+func (c *KibanaCollector) authConfig() kibana.AuthConfig {
+	return kibana.AuthConfig{
+		Username: c.config.Username,
+		Password: c.config.Password,
+		Params:   c.config.AuthParams,
+	}
+}
+
+// setAuth creates the auth provider for credentials and prepares the status
+// request with it. It is called with authMutex held or from the constructor.
+func (c *KibanaCollector) setAuth(credentials kibana.AuthConfig) {
+	state := &authState{username: credentials.Username}
+	state.provider, state.err = kibana.NewAuthProvider(c.config.AuthProvider, credentials)
+	state.statusRequest, state.statusRequestErr = c.newStatusRequest(state)
+	c.auth.Store(state)
+}
+
+// reloadSecrets reads the credential files, if any, and when one changed
+// creates the auth provider again with the new credentials
+func (c *KibanaCollector) reloadSecrets() error {
+	if c.secrets == nil {
+		return nil
+	}
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	changed, err := c.secrets.read()
+	if err != nil {
+		return newScrapeError(ErrRequest, "reading credentials: %w", err)
+	}
+	if changed {
+		c.setAuth(c.secrets.apply(c.authConfig()))
+	}
+	return nil
+}
+
+// newCollectDuration creates the histogram of end-to-end Collect durations,
+// which include waiting for a concurrent scrape and processing the responses
+func newCollectDuration(native bool) prometheus.Histogram {
+	opts := prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "collect_duration_seconds",
+		Help:      "End-to-end duration of the exporter's Collect calls, including the Kibana requests",
+		Buckets:   prometheus.DefBuckets,
+	}
+	if native {
+		opts.NativeHistogramBucketFactor = 1.1
+		opts.NativeHistogramMaxBucketNumber = 100
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+	return prometheus.NewHistogram(opts)
+}
+
+// newStatusRequest prepares the /api/status request used by scrapes with
+// the credentials of auth
+func (c *KibanaCollector) newStatusRequest(auth *authState) (*http.Request, error) {
+	req, err := http.NewRequest("GET", c.config.KibanaURL+"/api/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.applyHeaders(req, auth); err != nil {
+		return nil, err
+	}
+	if !c.config.Transport.DisableCompression {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	return req, nil
+}
+
+// setHeaders adds authentication and the Kibana API headers to req
+func (c *KibanaCollector) setHeaders(req *http.Request) error {
+	return c.applyHeaders(req, c.auth.Load())
+}
+
+// applyHeaders adds the credentials of auth and the Kibana API headers to req
+func (c *KibanaCollector) applyHeaders(req *http.Request, auth *authState) error {
+	if auth.err != nil {
+		return auth.err
+	}
+	if err := auth.provider.Apply(req); err != nil {
+		return fmt.Errorf("applying credentials: %w", err)
+	}
+	xsrf := c.config.XSRFValue
+	if xsrf == "" {
+		xsrf = "true"
+	}
+	req.Header.Set("kbn-xsrf", xsrf)
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	if c.config.APIVersion != "" {
+		req.Header.Set("elastic-api-version", c.config.APIVersion)
+	}
+	if c.config.InternalOrigin {
+		req.Header.Set("x-elastic-internal-origin", "kibana")
+	}
+	return nil
+}
+
+// refreshAuth renews the credentials after Kibana rejected them and prepares
+// the status request again with the new ones
+func (c *KibanaCollector) refreshAuth() {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	auth := c.auth.Load()
+	if auth.err != nil {
+		return
+	}
+	if err := auth.provider.Refresh(); err != nil {
+		log.WithError(err).WithField("kibana_url", c.config.KibanaURL).Warn("Failed to refresh Kibana credentials")
+		return
+	}
+	refreshed := *auth
+	refreshed.statusRequest, refreshed.statusRequestErr = c.newStatusRequest(&refreshed)
+	c.auth.Store(&refreshed)
 }
 
 // Describe implements prometheus.Collector
@@ -220,13 +660,17 @@ func (c *KibanaCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.statusCore
 	ch <- c.statusElastic
 	ch <- c.statusSavedObjects
+	c.savedObjects.describe(ch)
 	ch <- c.heapTotal
 	ch <- c.heapUsed
 	ch <- c.heapSizeLimit
+	ch <- c.heapRatio
+	ch <- c.heapPressure
 	ch <- c.residentSet
 	ch <- c.eventLoop
 	ch <- c.requestsTotal
-	ch <- c.responseTime
+	ch <- c.requestsRate
+	c.responseTime.describe(ch)
 	ch <- c.concurrentConn
 	ch <- c.uptime
 	ch <- c.processMemory
@@ -239,102 +683,570 @@ func (c *KibanaCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.osMemUsed
 	ch <- c.scrapeDuration
 	ch <- c.scrapeSuccess
+	ch <- c.lastErrorTime
+	ch <- c.payloadBytes
+	ch <- c.throttled
+	c.certs.describe(ch)
+	c.phaseDescs.describe(ch)
+	c.transitions.describe(ch)
+	c.shape.describe(ch)
+	ch <- c.clockSkew
+	c.restarts.describe(ch)
+	ch <- c.forbidden.desc
+	c.deadlines.describe(ch)
+	c.responses.describe(ch)
+	c.coalesce.describe(ch)
+	ch <- c.series.desc
+	ch <- c.downtime.desc
+	ch <- c.disconnects.desc
+	if c.memTrend != nil {
+		c.memTrend.describe(ch)
+	}
+	if c.plugins != nil {
+		c.plugins.describe(ch)
+	}
+	if c.headers != nil {
+		c.headers.describe(ch)
+	}
+	c.collectDuration.Describe(ch)
+	if c.slow != nil {
+		ch <- c.slow.desc
+	}
+	if c.watchdog != nil {
+		ch <- c.watchdog.desc
+	}
+	if c.resolver != nil {
+		ch <- c.dnsChanges
+		ch <- c.dnsFailures
+	}
+	if c.authCheck() {
+		ch <- c.authOK
+	}
+	if c.config.Spaces.Enabled {
+		c.spaces.describe(ch)
+	}
+	if len(c.config.UISettings) > 0 {
+		c.uiSettings.describe(ch)
+	}
+	if c.config.ClusterInfo {
+		ch <- c.clusterInfo
+	}
+	if c.config.Deprecations {
+		c.deprecations.describe(ch)
+	}
+	if c.config.SavedObjectsProbe != "" {
+		c.savedObjectsProbe.describe(ch)
+	}
+	if c.config.FrontendProbe != "" {
+		c.frontendProbe.describe(ch)
+	}
+	if len(c.config.OpenSearchPlugins) > 0 {
+		c.openSearch.describe(ch)
+	}
+	if c.customSuccess != nil {
+		ch <- c.customSuccess
+		for _, ep := range c.custom {
+			for _, m := range ep.metrics {
+				ch <- m.desc
+			}
+		}
+	}
+	if c.config.Sidecar {
+		ch <- c.startupPhase
+	}
 }
 
 // Collect implements prometheus.Collector
 func (c *KibanaCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch, nil)
+}
+
+// collect runs the selected collectors. The optional collectors only run
+// once the status scrape succeeded, unless status is not selected.
+func (c *KibanaCollector) collect(ch chan<- prometheus.Metric, selected selection) {
+	if c.paused() {
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		c.collectDuration.Observe(time.Since(start).Seconds())
+		ch <- c.collectDuration
+	}()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	defer c.deadlines.export(ch)
+	defer c.responses.export(ch)
+	defer c.series.export(ch)
+	defer c.downtime.export(ch)
+	if c.watchdog != nil {
+		defer c.watchdog.export(ch)
+	}
+	if c.downtime.check(c.config.KibanaURL, start) {
+		return
+	}
+	defer c.coalesce.export(ch)
+	c.coalesce.run(ch, selected, start, func(ch chan<- prometheus.Metric) {
+		c.scrape(ch, selected, start)
+	})
+}
+
+// scrape fetches the selected collectors' metrics from Kibana. Called with
+// the collector mutex held.
+func (c *KibanaCollector) scrape(ch chan<- prometheus.Metric, selected selection, start time.Time) {
+	timings := newScrapeTimings(start)
+	timings.lap("wait")
+	var phases *scrapePhases
+	if c.slow != nil {
+		defer func() { c.slow.observe(ch, c.config.KibanaURL, start, timings, phases) }()
+	}
 
+	ctx := context.Background()
+	if c.watchdog != nil {
+		var stop func()
+		ctx, stop = c.watchdog.watch(ctx, c.config.KibanaURL, c.transport.CloseIdleConnections)
+		defer stop()
+	}
+	ctx, span := c.config.Tracer.Start(ctx, "kibana.scrape", spanKindInternal)
+	defer span.End()
+	span.SetAttribute("kibana.url", c.config.KibanaURL)
+
+	c.series.reset()
+	if selected.has(CollectorStatus) {
+		ok := c.runCollector(ctx, ch, CollectorStatus, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			return c.collectStatus(ctx, span, ch)
+		})
+		timings.lap(CollectorStatus)
+		phases = &c.phases
+		if !ok {
+			return
+		}
+		if c.plugins != nil {
+			c.series.split(CollectorStatus, pluginStatusCollector, c.plugins.series)
+		}
+	}
+	if selected.has(CollectorAuth) && c.authCheck() {
+		c.runCollector(ctx, ch, CollectorAuth, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectAuth(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorAuth)
+	}
+	if selected.has(CollectorSpaces) && c.config.Spaces.Enabled {
+		c.runCollector(ctx, ch, CollectorSpaces, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectSpaces(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorSpaces)
+	}
+	if selected.has(CollectorUISettings) && len(c.config.UISettings) > 0 {
+		c.runCollector(ctx, ch, CollectorUISettings, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectUISettings(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorUISettings)
+	}
+	if selected.has(CollectorClusterInfo) && c.config.ClusterInfo {
+		c.runCollector(ctx, ch, CollectorClusterInfo, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectClusterInfo(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorClusterInfo)
+	}
+	if selected.has(CollectorDeprecations) && c.config.Deprecations {
+		c.runCollector(ctx, ch, CollectorDeprecations, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectDeprecations(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorDeprecations)
+	}
+	if selected.has(CollectorSavedObjectsProbe) && c.config.SavedObjectsProbe != "" {
+		c.runCollector(ctx, ch, CollectorSavedObjectsProbe, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectSavedObjectsProbe(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorSavedObjectsProbe)
+	}
+	if selected.has(CollectorFrontend) && c.config.FrontendProbe != "" {
+		c.runCollector(ctx, ch, CollectorFrontend, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectFrontendProbe(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorFrontend)
+	}
+	if selected.has(CollectorOpenSearchPlugins) && len(c.config.OpenSearchPlugins) > 0 {
+		c.runCollector(ctx, ch, CollectorOpenSearchPlugins, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectOpenSearchPlugins(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorOpenSearchPlugins)
+	}
+	if selected.has(CollectorCustom) && len(c.custom) > 0 {
+		c.runCollector(ctx, ch, CollectorCustom, func(ctx context.Context, ch chan<- prometheus.Metric) bool {
+			c.collectCustom(ctx, ch)
+			return true
+		})
+		timings.lap(CollectorCustom)
+	}
+	c.forbidden.export(ch)
+}
+
+// runCollector runs a collector with its configured time budget, if any, once
+// a worker of its pool is free, and counts the series it sends
+func (c *KibanaCollector) runCollector(ctx context.Context, ch chan<- prometheus.Metric, name string, collect func(context.Context, chan<- prometheus.Metric) bool) bool {
+	if timeout := c.config.CollectorTimeouts[name]; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release, err := c.config.WorkerPools.acquire(ctx, name)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"target": c.config.KibanaURL, "collector": name}).Warn("Skipping collector")
+		if ctx.Err() == context.DeadlineExceeded {
+			c.deadlines.observe(name)
+		}
+		return false
+	}
+	defer release()
+	ok := c.series.count(ch, name, func(ch chan<- prometheus.Metric) bool { return collect(ctx, ch) })
+	if ctx.Err() == context.DeadlineExceeded {
+		c.deadlines.observe(name)
+	}
+	return ok
+}
+
+// collectStatus scrapes /api/status and exports its metrics along with the
+// exporter's own metrics for the target. It reports whether the scrape succeeded.
+func (c *KibanaCollector) collectStatus(ctx context.Context, span Span, ch chan<- prometheus.Metric) bool {
+	requestCtx := ctx
+	if c.adaptive != nil {
+		timeout := c.adaptive.timeout(c.history.list())
+		c.deadlines.budgets[CollectorStatus] = timeout
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	start := time.Now()
-	status, err := c.scrapeKibana()
-	duration := time.Since(start).Seconds()
+	status, err := c.scrapeKibana(requestCtx)
+	duration := time.Since(start)
+	c.tracker.record(start, duration, err)
+	c.history.add(newSample(start, duration, status, err))
+	c.observeState(status, err)
+	// A budget that ran out is counted by runCollector; count the request
+	// timeout here so a single scrape is not counted twice.
+	if errors.Is(err, ErrTimeout) && ctx.Err() == nil {
+		c.deadlines.observe(CollectorStatus)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, duration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.lastErrorTime, prometheus.GaugeValue, unixSeconds(c.tracker.lastErrorTime()))
+	ch <- prometheus.MustNewConstMetric(c.payloadBytes, prometheus.GaugeValue, float64(c.payloadWire), "wire")
+	ch <- prometheus.MustNewConstMetric(c.payloadBytes, prometheus.GaugeValue, float64(c.payloadDecoded), "decoded")
+	ch <- prometheus.MustNewConstMetric(c.throttled, prometheus.CounterValue, float64(c.throttle.total.Load()))
+	c.certs.export(ch, c.peerCerts, time.Now())
+	c.phaseDescs.export(ch, c.phases)
+	if c.headers != nil {
+		c.headers.export(ch)
+	}
+	if c.resolver != nil {
+		ch <- prometheus.MustNewConstMetric(c.dnsChanges, prometheus.CounterValue, float64(c.resolver.changes.Load()))
+		ch <- prometheus.MustNewConstMetric(c.dnsFailures, prometheus.CounterValue, float64(c.resolver.failures.Load()))
+	}
 
-	ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, duration)
+	if c.config.Sidecar {
+		phase := startupPhase(status, err)
+		for _, p := range startupPhases {
+			value := 0.0
+			if p == phase {
+				value = 1.0
+			}
+			ch <- prometheus.MustNewConstMetric(c.startupPhase, prometheus.GaugeValue, value, p)
+		}
+	}
 
 	if err != nil {
-		log.WithError(err).Error("Failed to scrape Kibana")
+		c.failureLog.Failure(err)
+		span.RecordError(err)
 		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
 		ch <- prometheus.MustNewConstMetric(c.scrapeSuccess, prometheus.GaugeValue, 0)
-		return
+		return false
 	}
 
+	c.failureLog.Success()
 	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
 	ch <- prometheus.MustNewConstMetric(c.scrapeSuccess, prometheus.GaugeValue, 1)
 
 	// Export metrics from status
 	c.exportStatus(ch, status)
+	c.exportClockSkew(ch, status, start, start.Add(duration))
+	return true
+}
+
+// State returns the outcome of the most recent scrapes
+func (c *KibanaCollector) State() ScrapeState {
+	state := c.tracker.snapshot()
+	state.URL = c.config.KibanaURL
+	return state
 }
 
-// CheckHealth checks if Kibana is reachable
+// History returns the most recent scrapes, oldest first
+func (c *KibanaCollector) History() []Sample {
+	return c.history.list()
+}
+
+// authCheck reports whether credentials are configured and should be verified
+func (c *KibanaCollector) authCheck() bool {
+	return c.config.AuthCheck && (c.config.Username != "" || c.config.UsernameFile != "")
+}
+
+// closeIdleConnections releases the connections to Kibana kept for reuse
+func (c *KibanaCollector) closeIdleConnections() {
+	c.transport.CloseIdleConnections()
+}
+
+func (c *KibanaCollector) paused() bool {
+	return c.config.paused != nil && c.config.paused.Load()
+}
+
+// Scrape fetches Kibana's status once, outside of a Prometheus scrape
+func (c *KibanaCollector) Scrape(ctx context.Context) (*KibanaStatus, error) {
+	return c.scrapeKibana(ctx)
+}
+
+// CheckHealth checks if Kibana is reachable. In sidecar mode, probing backs off
+// while Kibana is unreachable or still running migrations.
 func (c *KibanaCollector) CheckHealth() error {
+	// Kibana is not contacted while paused or in downtime, so there is nothing to check
+	if c.paused() || c.downtime.covers(time.Now()) {
+		return nil
+	}
+	if c.config.Sidecar {
+		return c.probe.check(c.checkHealth)
+	}
+	return c.checkHealth()
+}
+
+func (c *KibanaCollector) checkHealth() error {
+	if err := c.reloadSecrets(); err != nil {
+		return err
+	}
 	req, err := http.NewRequest("GET", c.config.KibanaURL+"/api/status", nil)
 	if err != nil {
-		return err
+		return &ScrapeError{Kind: ErrRequest, Err: err}
 	}
 
-	if c.config.Username != "" {
-		req.SetBasicAuth(c.config.Username, c.config.Password)
+	if err := c.setHeaders(req); err != nil {
+		return &ScrapeError{Kind: ErrRequest, Err: err}
+	}
+
+	if err := c.throttle.check(); err != nil {
+		return err
 	}
-	req.Header.Set("kbn-xsrf", "true")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return classifyTransportError(err)
 	}
 	defer resp.Body.Close()
+	c.throttle.observe(resp)
 
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return classifyRedirect(resp.StatusCode, resp.Header.Get("Location"))
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("kibana returned status %d", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return classifyStatus(resp.StatusCode, string(msg))
 	}
 
 	return nil
 }
 
-func (c *KibanaCollector) scrapeKibana() (*KibanaStatus, error) {
-	req, err := http.NewRequest("GET", c.config.KibanaURL+"/api/status", nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+func (c *KibanaCollector) scrapeKibana(ctx context.Context) (status *KibanaStatus, err error) {
+	ctx, span := c.startRequestSpan(ctx, "/api/status")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	c.phases = scrapePhases{}
+	ctx = traceConnection(ctx, span, &c.phases)
+	if c.headers != nil {
+		c.headers.reset()
 	}
 
-	if c.config.Username != "" {
-		req.SetBasicAuth(c.config.Username, c.config.Password)
+	if err := c.reloadSecrets(); err != nil {
+		return nil, err
+	}
+	auth := c.auth.Load()
+	if auth.statusRequestErr != nil {
+		return nil, newScrapeError(ErrRequest, "creating request: %w", auth.statusRequestErr)
+	}
+	if err := c.throttle.check(); err != nil {
+		return nil, err
+	}
+	// The prepared request is shared between scrapes; only clone its headers when they change
+	req := auth.statusRequest.WithContext(ctx)
+	if traceParent := span.TraceParent(); traceParent != "" {
+		req = auth.statusRequest.Clone(ctx)
+		req.Header.Set("traceparent", traceParent)
 	}
-	req.Header.Set("kbn-xsrf", "true")
 
-	log.WithField("url", c.config.KibanaURL+"/api/status").Debug("Scraping Kibana")
+	if log.IsLevelEnabled(log.DebugLevel) {
+		log.WithField("url", c.config.KibanaURL+"/api/status").Debug("Scraping Kibana")
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil, classifyTransportError(err)
 	}
 	defer resp.Body.Close()
+	c.throttle.observe(resp)
+	if c.headers != nil {
+		c.headers.observe(resp)
+	}
+	if resp.TLS != nil {
+		c.peerCerts = resp.TLS.PeerCertificates
+	}
+
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	span.SetAttribute("http.protocol", resp.Proto)
+	wire := &countingReader{r: resp.Body}
+	decoded := &countingReader{r: wire}
+	defer func() {
+		c.payloadWire, c.payloadDecoded = wire.n, decoded.n
+		span.SetAttribute("http.response_content_length", wire.n)
+		span.SetAttribute("http.response_content_length_uncompressed", decoded.n)
+	}()
+
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := getGzipReader(wire)
+		if err != nil {
+			return nil, newScrapeError(ErrSchema, "decompressing response: %w", err)
+		}
+		defer putGzipReader(gz)
+		decoded.r = gz
+	}
 
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return nil, classifyRedirect(resp.StatusCode, resp.Header.Get("Location"))
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusUnauthorized {
+			c.refreshAuth()
+		}
+		msg, _ := io.ReadAll(io.LimitReader(decoded, 1024))
+		if c.config.ElasticCloud {
+			if err := classifyCloudProxy(resp, string(msg)); err != nil {
+				return nil, err
+			}
+		}
+		return nil, classifyStatus(resp.StatusCode, string(msg))
+	}
+	if redirectedAway(resp, req.URL) {
+		return nil, classifyRedirect(resp.StatusCode, resp.Request.URL.Redacted())
 	}
 
-	var status KibanaStatus
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(decoded); err != nil {
+		return nil, classifyTransportError(err)
+	}
+	if !c.phases.firstByte.IsZero() {
+		c.phases.transfer = time.Since(c.phases.firstByte)
 	}
 
-	return &status, nil
+	status = &KibanaStatus{}
+	if err := json.Unmarshal(buf.Bytes(), status); err != nil {
+		return nil, newScrapeError(ErrSchema, "decoding response: %w", err)
+	}
+	schema := detectSchema(status, c.config.Schema)
+	if err := normalizeStatus(status, c.config.Schema); err != nil {
+		return nil, err
+	}
+	c.tracker.setPayload(schema, status)
+	c.shape.observe(c.config.KibanaURL, buf.Bytes())
+
+	return status, nil
 }
 
-func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *KibanaStatus) {
-	// Overall status
-	statusValue := -1.0
-	switch status.Status.Overall.Level {
+// traceConnection records DNS, connect, TLS and server time in phases and,
+// when tracing, as span events
+func traceConnection(ctx context.Context, span Span, phases *scrapePhases) context.Context {
+	var dnsStart, connectStart, tlsStart, wroteRequest time.Time
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			phases.dns = time.Since(dnsStart)
+			span.AddEvent("dns", map[string]interface{}{"duration_ms": msSince(dnsStart)})
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(network, addr string, err error) {
+			phases.connect = time.Since(connectStart)
+			span.AddEvent("connect", map[string]interface{}{"duration_ms": msSince(connectStart), "net.peer.addr": addr})
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			phases.tls = time.Since(tlsStart)
+			span.AddEvent("tls_handshake", map[string]interface{}{"duration_ms": msSince(tlsStart)})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			phases.reused = info.Reused
+			span.SetAttribute("net.conn_reused", info.Reused)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() {
+			phases.firstByte = time.Now()
+			phases.server = time.Since(wroteRequest)
+			span.AddEvent("first_byte", map[string]interface{}{"server_time_ms": msSince(wroteRequest)})
+		},
+	})
+}
+
+// overallStatusValue maps a status level to 1 (green), 0.5 (yellow), 0 (red) or -1 (unknown)
+func overallStatusValue(level string) float64 {
+	switch level {
 	case "available", "green":
-		statusValue = 1.0
+		return 1.0
 	case "degraded", "yellow":
-		statusValue = 0.5
+		return 0.5
 	case "unavailable", "red":
-		statusValue = 0.0
+		return 0.0
+	}
+	return -1.0
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+func msSince(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
 	}
-	ch <- prometheus.MustNewConstMetric(c.statusOverall, prometheus.GaugeValue, statusValue)
+	return float64(time.Since(t).Microseconds()) / 1000.0
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *KibanaStatus) {
+	// Overall status
+	ch <- prometheus.MustNewConstMetric(c.statusOverall, prometheus.GaugeValue, overallStatusValue(status.Status.Overall.Level))
+	c.transitions.observe(c.config.KibanaURL, status.Status.Overall.Level)
+	c.transitions.export(ch)
+	c.shape.export(ch)
 
 	// Core services status
 	for name, svc := range status.Status.Core {
@@ -345,6 +1257,10 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 		ch <- prometheus.MustNewConstMetric(c.statusCore, prometheus.GaugeValue, value, name)
 	}
 
+	if c.plugins != nil {
+		c.plugins.export(ch, c.config.KibanaURL, status.Status.Plugins)
+	}
+
 	// Elasticsearch status
 	if status.Status.Core["elasticsearch"] != nil {
 		value := 0.0
@@ -361,6 +1277,7 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 			value = 1.0
 		}
 		ch <- prometheus.MustNewConstMetric(c.statusSavedObjects, prometheus.GaugeValue, value)
+		c.savedObjects.export(ch, status.Status.Core["savedObjects"])
 	}
 
 	// Process memory metrics
@@ -370,6 +1287,15 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 			ch <- prometheus.MustNewConstMetric(c.heapTotal, prometheus.GaugeValue, float64(mem.Heap.TotalBytes))
 			ch <- prometheus.MustNewConstMetric(c.heapUsed, prometheus.GaugeValue, float64(mem.Heap.UsedBytes))
 			ch <- prometheus.MustNewConstMetric(c.heapSizeLimit, prometheus.GaugeValue, float64(mem.Heap.SizeLimit))
+			if mem.Heap.SizeLimit > 0 {
+				ratio := float64(mem.Heap.UsedBytes) / float64(mem.Heap.SizeLimit)
+				pressure := 0.0
+				if c.config.HeapPressureThreshold > 0 && ratio >= c.config.HeapPressureThreshold {
+					pressure = 1
+				}
+				ch <- prometheus.MustNewConstMetric(c.heapRatio, prometheus.GaugeValue, ratio)
+				ch <- prometheus.MustNewConstMetric(c.heapPressure, prometheus.GaugeValue, pressure)
+			}
 		}
 		if mem.Resident != nil {
 			ch <- prometheus.MustNewConstMetric(c.residentSet, prometheus.GaugeValue, float64(*mem.Resident))
@@ -384,6 +1310,13 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 	// Uptime
 	if status.Metrics.Process.Uptime != nil {
 		ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.GaugeValue, *status.Metrics.Process.Uptime/1000.0)
+		c.restarts.observe(c.config.KibanaURL, time.Duration(*status.Metrics.Process.Uptime*float64(time.Millisecond)))
+		c.restarts.export(ch)
+	}
+	if c.memTrend != nil && status.Metrics.Process.Memory != nil && status.Metrics.Process.Memory.Heap != nil {
+		mem := status.Metrics.Process.Memory
+		c.memTrend.observe(status.Metrics.CollectedAt, float64(mem.Heap.UsedBytes), mem.Resident, c.restarts.restarts)
+		c.memTrend.export(ch)
 	}
 
 	// Request metrics
@@ -391,9 +1324,13 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 		reqs := status.Metrics.Requests
 		if reqs.Total != nil {
 			ch <- prometheus.MustNewConstMetric(c.requestsTotal, prometheus.CounterValue, float64(*reqs.Total), "total")
+			if rate, ok := c.requestRate.update(status.Metrics.CollectedAt, status.Metrics.CollectionInterval, float64(*reqs.Total)); ok {
+				ch <- prometheus.MustNewConstMetric(c.requestsRate, prometheus.GaugeValue, rate)
+			}
 		}
 		if reqs.Disconnects != nil {
-			ch <- prometheus.MustNewConstMetric(c.requestsTotal, prometheus.CounterValue, float64(*reqs.Disconnects), "disconnects")
+			c.disconnects.observe(status.Metrics.CollectedAt, float64(*reqs.Disconnects))
+			c.disconnects.export(ch)
 		}
 		if reqs.StatusCodes != nil {
 			for code, count := range reqs.StatusCodes {
@@ -409,13 +1346,7 @@ func (c *KibanaCollector) exportStatus(ch chan<- prometheus.Metric, status *Kiba
 
 	// Response time
 	if status.Metrics.ResponseTimes != nil {
-		rt := status.Metrics.ResponseTimes
-		if rt.Avg != nil {
-			ch <- prometheus.MustNewConstMetric(c.responseTime, prometheus.GaugeValue, *rt.Avg/1000.0, "avg")
-		}
-		if rt.Max != nil {
-			ch <- prometheus.MustNewConstMetric(c.responseTime, prometheus.GaugeValue, *rt.Max/1000.0, "max")
-		}
+		c.responseTime.export(ch, status.Metrics.ResponseTimes)
 	}
 
 	// OS metrics
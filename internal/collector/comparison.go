@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DeploymentLabel is the target label that names a deployment in comparison mode
+const DeploymentLabel = "deployment"
+
+// Comparison exports difference metrics between two deployments of Kibana,
+// e.g. the old and new version during a blue/green upgrade. Both are regular
+// targets labeled with DeploymentLabel; the comparison uses their latest
+// scrapes, so it can lag the per-target metrics by one scrape.
+type Comparison struct {
+	targets   *Targets
+	baseline  string
+	candidate string
+
+	parity            *prometheus.Desc
+	responseTimeDelta *prometheus.Desc
+}
+
+// NewComparison compares the targets labeled deployment=baseline and deployment=candidate
+func NewComparison(targets *Targets, baseline, candidate string) *Comparison {
+	labels := prometheus.Labels{"baseline": baseline, "candidate": candidate}
+	return &Comparison{
+		targets:   targets,
+		baseline:  baseline,
+		candidate: candidate,
+		parity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "deployment", "status_parity"),
+			"Whether both deployments are up and report the same overall status",
+			nil, labels,
+		),
+		responseTimeDelta: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "deployment", "response_time_delta_seconds"),
+			"Average response time of the candidate minus that of the baseline deployment",
+			nil, labels,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Comparison) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.parity
+	ch <- c.responseTimeDelta
+}
+
+// Collect implements prometheus.Collector
+func (c *Comparison) Collect(ch chan<- prometheus.Metric) {
+	baseline, ok := c.targets.latestSample(DeploymentLabel, c.baseline)
+	if !ok {
+		return
+	}
+	candidate, ok := c.targets.latestSample(DeploymentLabel, c.candidate)
+	if !ok {
+		return
+	}
+
+	parity := 0.0
+	if baseline.Up && candidate.Up && baseline.Status == candidate.Status {
+		parity = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.parity, prometheus.GaugeValue, parity)
+	if baseline.Up && candidate.Up {
+		ch <- prometheus.MustNewConstMetric(c.responseTimeDelta, prometheus.GaugeValue, candidate.ResponseTime-baseline.ResponseTime)
+	}
+}
@@ -0,0 +1,287 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// CustomEndpoint maps values of an arbitrary Kibana API response to metrics
+type CustomEndpoint struct {
+	// Path is the API path, e.g. /api/task_manager/_health
+	Path    string         `json:"path"`
+	Metrics []CustomMetric `json:"metrics"`
+}
+
+// CustomMetric selects values with a JSONPath expression and exports them as a metric
+type CustomMetric struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Type is gauge (default) or counter
+	Type string `json:"type"`
+	// Path selects the values, e.g. $.stats.workload.value.task_types.*.count
+	Path string `json:"path"`
+	// Labels are literal values, or $1, $2... for the keys matched by the wildcards in Path
+	Labels map[string]string `json:"labels"`
+	// ValueMap converts string values, e.g. {"OK": 1, "warn": 0.5, "error": 0}
+	ValueMap map[string]float64 `json:"value_map"`
+	// Scale multiplies every value, e.g. 0.001 to convert milliseconds to seconds
+	Scale float64 `json:"scale"`
+}
+
+// customEndpointsFile is the format of the custom metrics file
+type customEndpointsFile struct {
+	Endpoints []CustomEndpoint `json:"endpoints"`
+}
+
+type customEndpoint struct {
+	path    string
+	metrics []customMetric
+}
+
+type customMetric struct {
+	desc        *prometheus.Desc
+	valueType   prometheus.ValueType
+	path        *jsonPath
+	labelValues []string
+	valueMap    map[string]float64
+	scale       float64
+}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	captureRE    = regexp.MustCompile(`^\$([1-9][0-9]*)$`)
+)
+
+// LoadCustomEndpoints reads and validates a JSON custom metrics file
+func LoadCustomEndpoints(file string) ([]CustomEndpoint, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config customEndpointsFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	if _, err := compileCustomEndpoints(config.Endpoints); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return config.Endpoints, nil
+}
+
+func compileCustomEndpoints(endpoints []CustomEndpoint) ([]customEndpoint, error) {
+	compiled := make([]customEndpoint, 0, len(endpoints))
+	names := make(map[string]bool)
+	for _, ep := range endpoints {
+		if !strings.HasPrefix(ep.Path, "/") {
+			return nil, fmt.Errorf("endpoint path %q must start with /", ep.Path)
+		}
+		c := customEndpoint{path: ep.Path}
+		for _, m := range ep.Metrics {
+			metric, err := compileCustomMetric(m)
+			if err != nil {
+				return nil, fmt.Errorf("endpoint %s: metric %q: %w", ep.Path, m.Name, err)
+			}
+			if names[m.Name] {
+				return nil, fmt.Errorf("endpoint %s: metric %q is defined more than once", ep.Path, m.Name)
+			}
+			names[m.Name] = true
+			c.metrics = append(c.metrics, metric)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileCustomMetric(m CustomMetric) (customMetric, error) {
+	if !metricNameRE.MatchString(m.Name) {
+		return customMetric{}, fmt.Errorf("invalid metric name")
+	}
+	path, err := compileJSONPath(m.Path)
+	if err != nil {
+		return customMetric{}, err
+	}
+
+	metric := customMetric{path: path, valueMap: m.ValueMap, scale: m.Scale}
+	switch m.Type {
+	case "", "gauge":
+		metric.valueType = prometheus.GaugeValue
+	case "counter":
+		metric.valueType = prometheus.CounterValue
+	default:
+		return customMetric{}, fmt.Errorf("unknown type %q, expected gauge or counter", m.Type)
+	}
+
+	labelNames := make([]string, 0, len(m.Labels))
+	for name := range m.Labels {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	wildcards := path.wildcards()
+	used := make([]bool, wildcards)
+	for _, name := range labelNames {
+		if !labelNameRE.MatchString(name) {
+			return customMetric{}, fmt.Errorf("invalid label name %q", name)
+		}
+		value := m.Labels[name]
+		if match := captureRE.FindStringSubmatch(value); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			if n > wildcards {
+				return customMetric{}, fmt.Errorf("label %s refers to %s but the path has %d wildcards", name, value, wildcards)
+			}
+			used[n-1] = true
+		}
+		metric.labelValues = append(metric.labelValues, value)
+	}
+	// Every wildcard must end up in a label, otherwise its matches collide
+	for i, ok := range used {
+		if !ok {
+			return customMetric{}, fmt.Errorf("wildcard $%d of the path is not used by any label", i+1)
+		}
+	}
+
+	help := m.Help
+	if help == "" {
+		help = "Custom metric from " + m.Path
+	}
+	metric.desc = prometheus.NewDesc(m.Name, help, labelNames, nil)
+	return metric, nil
+}
+
+// collectCustom scrapes the custom endpoints and exports their metrics
+func (c *KibanaCollector) collectCustom(ctx context.Context, ch chan<- prometheus.Metric) {
+	for _, ep := range c.custom {
+		if c.forbidden.skip(CollectorCustom, ep.path) {
+			ch <- prometheus.MustNewConstMetric(c.customSuccess, prometheus.GaugeValue, 0, ep.path)
+			continue
+		}
+		var doc interface{}
+		err := c.getJSON(ctx, ep.path, &doc)
+		success := 1.0
+		if err != nil {
+			success = 0
+		}
+		if err != nil && !c.forbidden.observe(c.config.KibanaURL, CollectorCustom, ep.path, err) {
+			log.WithError(err).WithFields(log.Fields{
+				"target":     c.config.KibanaURL,
+				"endpoint":   ep.path,
+				"error_code": ErrorCode(err),
+			}).Warn("Failed to scrape custom endpoint")
+		}
+		ch <- prometheus.MustNewConstMetric(c.customSuccess, prometheus.GaugeValue, success, ep.path)
+		if err != nil {
+			continue
+		}
+
+		for _, m := range ep.metrics {
+			for _, match := range m.path.eval(doc) {
+				value, ok := m.value(match.value)
+				if !ok {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, value, m.labels(match.captures)...)
+			}
+		}
+	}
+}
+
+// value converts a JSON value to a sample value
+func (m customMetric) value(v interface{}) (float64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case bool:
+		if v {
+			f = 1
+		}
+	case string:
+		if mapped, ok := m.valueMap[v]; ok {
+			f = mapped
+		} else if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			f = parsed
+		} else {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if m.scale != 0 {
+		f *= m.scale
+	}
+	return f, true
+}
+
+// labels resolves $n label values to wildcard captures
+func (m customMetric) labels(captures []string) []string {
+	values := make([]string, len(m.labelValues))
+	for i, value := range m.labelValues {
+		if match := captureRE.FindStringSubmatch(value); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			value = captures[n-1]
+		}
+		values[i] = value
+	}
+	return values
+}
+
+// getJSON fetches a Kibana API path and decodes the JSON response into v
+func (c *KibanaCollector) getJSON(ctx context.Context, path string, v interface{}) (err error) {
+	ctx, span := c.startRequestSpan(ctx, path)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.KibanaURL+path, nil)
+	if err != nil {
+		return &ScrapeError{Kind: ErrRequest, Err: err}
+	}
+	if err := c.setHeaders(req); err != nil {
+		return &ScrapeError{Kind: ErrRequest, Err: err}
+	}
+	if strings.HasPrefix(path, "/internal/") {
+		req.Header.Set("x-elastic-internal-origin", "kibana")
+	}
+	if traceParent := span.TraceParent(); traceParent != "" {
+		req.Header.Set("traceparent", traceParent)
+	}
+
+	if err := c.throttle.check(); err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	defer resp.Body.Close()
+	c.throttle.observe(resp)
+	span.SetAttribute("http.status_code", resp.StatusCode)
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return classifyRedirect(resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return classifyStatus(resp.StatusCode, string(msg))
+	}
+	if redirectedAway(resp, req.URL) {
+		return classifyRedirect(resp.StatusCode, resp.Request.URL.Redacted())
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return newScrapeError(ErrSchema, "decoding response: %w", err)
+	}
+	return nil
+}
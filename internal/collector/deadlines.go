@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeDeadlines counts scrapes that ran out of time, per collector, and
+// exports the budget each collector runs under. Guarded by the collector mutex.
+type scrapeDeadlines struct {
+	budgets  map[string]time.Duration
+	exceeded map[string]uint64
+
+	exceededDesc *prometheus.Desc
+	timeoutDesc  *prometheus.Desc
+}
+
+// newScrapeDeadlines derives the per-collector budgets from the request
+// timeout and the optional collector timeouts. The status collector is bound
+// by whichever of the two is shorter.
+func newScrapeDeadlines(timeout time.Duration, collectorTimeouts map[string]time.Duration) scrapeDeadlines {
+	budgets := make(map[string]time.Duration, len(collectorTimeouts)+1)
+	for name, t := range collectorTimeouts {
+		if t > 0 {
+			budgets[name] = t
+		}
+	}
+	if t, ok := budgets[CollectorStatus]; !ok || (timeout > 0 && timeout < t) {
+		budgets[CollectorStatus] = timeout
+	}
+	return scrapeDeadlines{
+		budgets:  budgets,
+		exceeded: map[string]uint64{CollectorStatus: 0},
+		exceededDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "scrape_deadline_exceeded_total"),
+			"Scrapes of a collector that ran out of time before Kibana answered",
+			[]string{"collector"}, nil,
+		),
+		timeoutDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "scrape_timeout_seconds"),
+			"Time budget a collector runs under during a scrape",
+			[]string{"collector"}, nil,
+		),
+	}
+}
+
+func (d *scrapeDeadlines) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.exceededDesc
+	ch <- d.timeoutDesc
+}
+
+// observe counts a scrape of the named collector that hit its deadline
+func (d *scrapeDeadlines) observe(name string) {
+	d.exceeded[name]++
+}
+
+func (d *scrapeDeadlines) export(ch chan<- prometheus.Metric) {
+	for _, name := range slices.Sorted(maps.Keys(d.budgets)) {
+		ch <- prometheus.MustNewConstMetric(d.timeoutDesc, prometheus.GaugeValue, d.budgets[name].Seconds(), name)
+		if _, ok := d.exceeded[name]; !ok {
+			d.exceeded[name] = 0
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.exceeded)) {
+		ch <- prometheus.MustNewConstMetric(d.exceededDesc, prometheus.CounterValue, float64(d.exceeded[name]), name)
+	}
+}
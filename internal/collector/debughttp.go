@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDebugHTTPMaxBytes is how much of an error response body is logged
+// when DebugHTTPConfig.MaxBytes is zero
+const DefaultDebugHTTPMaxBytes = 1024
+
+// maxDebugBodyRead bounds how much of an error response is buffered for logging
+const maxDebugBodyRead = 1 << 20
+
+// redactedValue replaces sensitive values in HTTP debug logs
+const redactedValue = "REDACTED"
+
+// defaultRedactedNames are headers, query parameters and JSON fields whose
+// values never appear in HTTP debug logs
+var defaultRedactedNames = []string{"authorization", "proxy-authorization", "cookie", "set-cookie", "password", "token", "api_key", "apikey", "secret"}
+
+// DebugHTTPConfig enables logging a summary of every request to Kibana
+type DebugHTTPConfig struct {
+	Enabled bool
+	// MaxBytes of an error response body are logged (DefaultDebugHTTPMaxBytes if zero)
+	MaxBytes int
+	// Redact are headers, query parameters and JSON fields redacted in
+	// addition to credentials, cookies and tokens
+	Redact []string
+}
+
+// debugTransport logs the method, redacted URL, status and duration of every
+// request and the redacted start of error responses, to diagnose auth and
+// proxy problems without a packet capture
+type debugTransport struct {
+	next     http.RoundTripper
+	maxBytes int
+	names    []string
+	// fields matches "name": "value" pairs of the redacted JSON fields
+	fields *regexp.Regexp
+}
+
+// wrapDebug adds HTTP debug logging to the transport if configured
+func wrapDebug(next http.RoundTripper, config DebugHTTPConfig) http.RoundTripper {
+	if !config.Enabled {
+		return next
+	}
+	t := &debugTransport{next: next, maxBytes: config.MaxBytes}
+	if t.maxBytes <= 0 {
+		t.maxBytes = DefaultDebugHTTPMaxBytes
+	}
+	quoted := make([]string, 0, len(defaultRedactedNames)+len(config.Redact))
+	for _, name := range slices.Concat(defaultRedactedNames, config.Redact) {
+		t.names = append(t.names, strings.ToLower(name))
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	t.fields = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	return t
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields := log.Fields{
+		"method":           req.Method,
+		"url":              t.redactURL(req),
+		"request_headers":  t.redactHeaders(req.Header),
+		"duration_seconds": time.Since(start).Seconds(),
+	}
+	if err != nil {
+		log.WithFields(fields).WithError(err).Info("Kibana HTTP request failed")
+		return resp, err
+	}
+	fields["status"] = resp.StatusCode
+	fields["content_type"] = resp.Header.Get("Content-Type")
+	if resp.StatusCode >= 400 {
+		fields["body"] = t.peekBody(resp)
+	}
+	log.WithFields(fields).Info("Kibana HTTP request")
+	return resp, nil
+}
+
+func (t *debugTransport) redacted(name string) bool {
+	return slices.Contains(t.names, strings.ToLower(name))
+}
+
+// redactURL returns the URL without its password and redacted query values
+func (t *debugTransport) redactURL(req *http.Request) string {
+	u := *req.URL
+	query := u.Query()
+	for name := range query {
+		if t.redacted(name) {
+			query.Set(name, redactedValue)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.Redacted()
+}
+
+// redactHeaders returns the request headers with redacted values; the
+// scheme of Authorization headers is kept to tell basic from API key auth
+func (t *debugTransport) redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if t.redacted(name) {
+			if scheme, _, ok := strings.Cut(value, " "); ok && strings.HasSuffix(name, "Authorization") {
+				value = scheme + " " + redactedValue
+			} else {
+				value = redactedValue
+			}
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// peekBody returns the redacted start of the response body and leaves the
+// body intact for the caller
+func (t *debugTransport) peekBody(resp *http.Response) string {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxDebugBodyRead))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), resp.Body), resp.Body}
+
+	body := raw
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		if gz, err := gzip.NewReader(bytes.NewReader(raw)); err == nil {
+			body, _ = io.ReadAll(io.LimitReader(gz, maxDebugBodyRead))
+		}
+	}
+	text := t.fields.ReplaceAllString(string(body), `$1"`+redactedValue+`"`)
+	if len(text) > t.maxBytes {
+		text = strings.ToValidUTF8(text[:t.maxBytes], "") + "..."
+	}
+	return text
+}
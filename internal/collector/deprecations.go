@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Upgrade Assistant endpoints read by the deprecations collector
+const (
+	kibanaDeprecationsEndpoint = "/api/deprecations/"
+	esDeprecationsEndpoint     = "/api/upgrade_assistant/es_deprecations"
+	upgradeStatusEndpoint      = "/api/upgrade_assistant/status"
+)
+
+type deprecationsResponse struct {
+	Deprecations []struct {
+		Level string `json:"level"`
+	} `json:"deprecations"`
+}
+
+type upgradeStatusResponse struct {
+	ReadyForUpgrade bool `json:"readyForUpgrade"`
+}
+
+type deprecationsDescs struct {
+	count *prometheus.Desc
+	ready *prometheus.Desc
+}
+
+func newDeprecationsDescs() deprecationsDescs {
+	return deprecationsDescs{
+		count: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "upgrade", "deprecations"),
+			"Deprecations reported by the Upgrade Assistant, by source (kibana or elasticsearch) and level",
+			[]string{"source", "level"}, nil,
+		),
+		ready: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "upgrade", "ready"),
+			"Whether the Upgrade Assistant reports the deployment ready for the next major version",
+			nil, nil,
+		),
+	}
+}
+
+func (d deprecationsDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.count
+	ch <- d.ready
+}
+
+// collectDeprecations exports the deprecation counts of Kibana and
+// Elasticsearch and the overall upgrade readiness. Critical and warning
+// counts are always exported, so that a burn-down reaches zero instead of
+// disappearing.
+func (c *KibanaCollector) collectDeprecations(ctx context.Context, ch chan<- prometheus.Metric) {
+	for _, source := range []struct{ name, endpoint string }{
+		{"kibana", kibanaDeprecationsEndpoint},
+		{"elasticsearch", esDeprecationsEndpoint},
+	} {
+		var response deprecationsResponse
+		if !c.getDeprecationsJSON(ctx, source.endpoint, &response) {
+			continue
+		}
+		counts := map[string]int{"critical": 0, "warning": 0}
+		for _, d := range response.Deprecations {
+			counts[d.Level]++
+		}
+		for _, level := range slices.Sorted(maps.Keys(counts)) {
+			ch <- prometheus.MustNewConstMetric(c.deprecations.count, prometheus.GaugeValue, float64(counts[level]), source.name, level)
+		}
+	}
+
+	var status upgradeStatusResponse
+	if c.getDeprecationsJSON(ctx, upgradeStatusEndpoint, &status) {
+		ready := 0.0
+		if status.ReadyForUpgrade {
+			ready = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.deprecations.ready, prometheus.GaugeValue, ready)
+	}
+}
+
+// getDeprecationsJSON fetches an Upgrade Assistant endpoint, skipping it
+// while it is forbidden, and reports whether v was filled
+func (c *KibanaCollector) getDeprecationsJSON(ctx context.Context, endpoint string, v interface{}) bool {
+	if c.forbidden.skip(CollectorDeprecations, endpoint) {
+		return false
+	}
+	err := c.getJSON(ctx, endpoint, v)
+	if err != nil && !c.forbidden.observe(c.config.KibanaURL, CollectorDeprecations, endpoint, err) {
+		log.WithError(err).WithFields(log.Fields{
+			"target":     c.config.KibanaURL,
+			"endpoint":   endpoint,
+			"error_code": ErrorCode(err),
+		}).Warn("Failed to scrape deprecations")
+	}
+	return err == nil
+}
@@ -0,0 +1,39 @@
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// disconnectCounter accumulates the disconnects Kibana reports into a
+// counter. Kibana counts them per collection interval and starts again at
+// zero for the next, so every new sample adds all of its disconnects.
+// Samples are told apart by collected_at, so repeated scrapes of one sample
+// count it once. Guarded by the collector mutex.
+type disconnectCounter struct {
+	total  float64
+	lastAt string
+	seen   bool
+	desc   *prometheus.Desc
+}
+
+func newDisconnectCounter() disconnectCounter {
+	return disconnectCounter{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "client", "disconnects_total"),
+			"Requests whose client disconnected before Kibana responded, accumulated over Kibana's collection intervals",
+			nil, nil,
+		),
+	}
+}
+
+func (d *disconnectCounter) observe(collectedAt string, disconnects float64) {
+	if d.seen && collectedAt != "" && collectedAt == d.lastAt {
+		return
+	}
+	d.total += disconnects
+	d.seen, d.lastAt = true, collectedAt
+}
+
+func (d *disconnectCounter) export(ch chan<- prometheus.Metric) {
+	if d.seen {
+		ch <- prometheus.MustNewConstMetric(d.desc, prometheus.CounterValue, d.total)
+	}
+}
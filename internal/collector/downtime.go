@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DowntimeWindow is a recurring period in which a target is deliberately
+// down and not scraped. The fields follow Alertmanager's time intervals:
+// Weekdays holds days or ranges such as "monday:friday" (every day if
+// empty), StartTime and EndTime are "HH:MM" with EndTime up to "24:00", and
+// Location is an IANA time zone (the exporter's local time if empty). A
+// window whose end is before its start runs past midnight into the next day.
+type DowntimeWindow struct {
+	Weekdays  []string `json:"weekdays,omitempty"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+	Location  string   `json:"location,omitempty"`
+
+	days       [7]bool
+	start, end int // minutes since midnight
+	location   *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// UnmarshalJSON parses and validates the window, so a bad window is rejected
+// wherever targets are read from
+func (w *DowntimeWindow) UnmarshalJSON(data []byte) error {
+	type plain DowntimeWindow
+	if err := json.Unmarshal(data, (*plain)(w)); err != nil {
+		return err
+	}
+	return w.parse()
+}
+
+func (w *DowntimeWindow) parse() error {
+	var err error
+	if w.start, err = parseClock(w.StartTime); err != nil {
+		return fmt.Errorf("downtime start_time: %w", err)
+	}
+	if w.end, err = parseClock(w.EndTime); err != nil {
+		return fmt.Errorf("downtime end_time: %w", err)
+	}
+	if w.start == w.end {
+		return fmt.Errorf("downtime window %s-%s is empty", w.StartTime, w.EndTime)
+	}
+
+	w.location = time.Local
+	if w.Location != "" {
+		if w.location, err = time.LoadLocation(w.Location); err != nil {
+			return fmt.Errorf("downtime location: %w", err)
+		}
+	}
+
+	w.days = [7]bool{}
+	if len(w.Weekdays) == 0 {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, spec := range w.Weekdays {
+		from, to, isRange := strings.Cut(strings.ToLower(spec), ":")
+		if !isRange {
+			to = from
+		}
+		first, ok1 := weekdays[from]
+		last, ok2 := weekdays[to]
+		if !ok1 || !ok2 {
+			return fmt.Errorf("downtime weekdays: invalid %q, expected a day such as monday or a range such as monday:friday", spec)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight, allowing "24:00"
+func parseClock(s string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(s, "%d:%d", &hours, &minutes); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time %q, expected 00:00 to 24:00", s)
+	}
+	return hours*60 + minutes, nil
+}
+
+// active reports whether t falls into the window
+func (w DowntimeWindow) active(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// Past midnight: the window belongs to the day it started
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && minute >= w.start) || (w.days[yesterday] && minute < w.end)
+}
+
+// downtime tracks a target's downtime windows. Guarded by the collector mutex.
+type downtime struct {
+	windows []DowntimeWindow
+	active  bool
+	desc    *prometheus.Desc
+}
+
+func newDowntime(windows []DowntimeWindow) downtime {
+	return downtime{
+		windows: windows,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "in_downtime"),
+			"Whether the target is in a configured downtime window and not scraped",
+			nil, nil,
+		),
+	}
+}
+
+// covers reports whether a downtime window is active at t. The windows never
+// change, so no lock is needed.
+func (d *downtime) covers(t time.Time) bool {
+	for _, w := range d.windows {
+		if w.active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// check reports whether a downtime window is active at t and logs when the
+// target enters or leaves one
+func (d *downtime) check(url string, t time.Time) bool {
+	active := d.covers(t)
+	if active != d.active {
+		d.active = active
+		if active {
+			log.WithField("kibana_url", url).Info("Target entered a downtime window, not scraping")
+		} else {
+			log.WithField("kibana_url", url).Info("Target left its downtime window, scraping resumed")
+		}
+	}
+	return active
+}
+
+func (d *downtime) export(ch chan<- prometheus.Metric) {
+	value := 0.0
+	if d.active {
+		value = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(d.desc, prometheus.GaugeValue, value)
+}
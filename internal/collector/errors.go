@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Error catalog. Each sentinel maps to a stable code that is safe to key
+// log-based alerts and runbooks on; the message text may change, the code will not.
+var (
+	ErrAuth       = errors.New("authentication failed")
+	ErrTimeout    = errors.New("request timed out")
+	ErrTLS        = errors.New("tls failure")
+	ErrConnection = errors.New("connection failed")
+	ErrHTTPStatus = errors.New("unexpected http status")
+	ErrSchema     = errors.New("unexpected response schema")
+	ErrRequest    = errors.New("invalid request")
+	ErrThrottled  = errors.New("throttled by kibana")
+)
+
+// Stable error codes
+const (
+	CodeAuth       = "auth"
+	CodeTimeout    = "timeout"
+	CodeTLS        = "tls"
+	CodeConnection = "connection"
+	CodeHTTPStatus = "http_status"
+	CodeSchema     = "schema"
+	CodeRequest    = "request"
+	CodeThrottled  = "throttled"
+	CodeUnknown    = "unknown"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrAuth, CodeAuth},
+	{ErrTimeout, CodeTimeout},
+	{ErrTLS, CodeTLS},
+	{ErrConnection, CodeConnection},
+	{ErrHTTPStatus, CodeHTTPStatus},
+	{ErrSchema, CodeSchema},
+	{ErrRequest, CodeRequest},
+	{ErrThrottled, CodeThrottled},
+}
+
+// ScrapeError wraps an underlying error with its catalog entry
+type ScrapeError struct {
+	Kind error
+	Err  error
+	// StatusCode and Body are set when Kibana answered with a non-200 status
+	StatusCode int
+	Body       string
+}
+
+func (e *ScrapeError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+// Unwrap allows errors.Is to match both the catalog entry and the cause
+func (e *ScrapeError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Code returns the stable code of the error
+func (e *ScrapeError) Code() string {
+	return ErrorCode(e.Kind)
+}
+
+// ErrorCode returns the stable code for err, or CodeUnknown if it is not in the catalog
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+	return CodeUnknown
+}
+
+func newScrapeError(kind error, format string, args ...interface{}) error {
+	return &ScrapeError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// classifyTransportError maps an error returned by http.Client.Do to the catalog
+func classifyTransportError(err error) error {
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+
+	var opErr *net.OpError
+	var scrapeErr *ScrapeError
+
+	switch {
+	// Errors from CheckRedirect are already classified
+	case errors.As(err, &scrapeErr):
+		return scrapeErr
+	// A dial timeout means Kibana is unreachable, not slow
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return &ScrapeError{Kind: ErrConnection, Err: err}
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		return &ScrapeError{Kind: ErrTLS, Err: err}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &ScrapeError{Kind: ErrTimeout, Err: err}
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return &ScrapeError{Kind: ErrTLS, Err: err}
+	default:
+		return &ScrapeError{Kind: ErrConnection, Err: err}
+	}
+}
+
+// classifyStatus maps a non-200 Kibana response to the catalog
+func classifyStatus(statusCode int, body string) error {
+	kind := ErrHTTPStatus
+	switch statusCode {
+	case 401, 403:
+		kind = ErrAuth
+	case 429:
+		kind = ErrThrottled
+	}
+	return &ScrapeError{
+		Kind:       kind,
+		Err:        fmt.Errorf("kibana returned status %d: %s", statusCode, body),
+		StatusCode: statusCode,
+		Body:       body,
+	}
+}
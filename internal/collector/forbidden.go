@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultForbiddenCooldown is how long an optional collector endpoint is
+// skipped after Kibana answered 403
+const DefaultForbiddenCooldown = 30 * time.Minute
+
+type forbiddenKey struct {
+	collector, endpoint string
+}
+
+// forbiddenEndpoints degrades optional collectors that the scrape account
+// lacks privileges for: a 403 skips the endpoint for a cooldown instead of
+// failing, and logging, on every scrape. Guarded by the collector mutex.
+type forbiddenEndpoints struct {
+	cooldown time.Duration
+	until    map[forbiddenKey]time.Time
+	logged   map[forbiddenKey]bool
+	desc     *prometheus.Desc
+}
+
+func newForbiddenEndpoints(cooldown time.Duration) forbiddenEndpoints {
+	if cooldown <= 0 {
+		cooldown = DefaultForbiddenCooldown
+	}
+	return forbiddenEndpoints{
+		cooldown: cooldown,
+		until:    make(map[forbiddenKey]time.Time),
+		logged:   make(map[forbiddenKey]bool),
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_forbidden"),
+			"Whether an optional collector endpoint is disabled for a cooldown after Kibana answered 403",
+			[]string{"collector", "endpoint"}, nil,
+		),
+	}
+}
+
+// skip reports whether the endpoint is cooling down after a 403
+func (f *forbiddenEndpoints) skip(collector, endpoint string) bool {
+	return time.Now().Before(f.until[forbiddenKey{collector, endpoint}])
+}
+
+// observe starts a cooldown if err is a 403 and reports whether it did. The
+// first 403 of an endpoint is logged with the privilege it needs.
+func (f *forbiddenEndpoints) observe(url, collector, endpoint string, err error) bool {
+	var scrapeErr *ScrapeError
+	if !errors.As(err, &scrapeErr) || scrapeErr.StatusCode != http.StatusForbidden {
+		return false
+	}
+	key := forbiddenKey{collector, endpoint}
+	f.until[key] = time.Now().Add(f.cooldown)
+	if !f.logged[key] {
+		f.logged[key] = true
+		fields := log.Fields{
+			"target":    url,
+			"collector": collector,
+			"endpoint":  endpoint,
+			"cooldown":  f.cooldown.String(),
+			"kibana":    forbiddenMessage(scrapeErr.Body),
+		}
+		if privilege, ok := RequiredPrivilege(collector); ok {
+			fields["required_privilege"] = privilege.String()
+		}
+		log.WithFields(fields).Warn("Kibana denied access to an optional collector, disabling it for the cooldown; grant the privilege or disable the collector")
+	}
+	return true
+}
+
+func (f *forbiddenEndpoints) export(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for key, until := range f.until {
+		value := 0.0
+		if now.Before(until) {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(f.desc, prometheus.GaugeValue, value, key.collector, key.endpoint)
+	}
+}
+
+// forbiddenMessage extracts the message of a Kibana error response, which
+// names the missing privilege
+func forbiddenMessage(body string) string {
+	var kibanaErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &kibanaErr) == nil && kibanaErr.Message != "" {
+		return kibanaErr.Message
+	}
+	return body
+}
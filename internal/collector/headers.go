@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxHeaderValueLength truncates captured header values so a misbehaving
+// proxy cannot put arbitrarily long label values into the exposition
+const maxHeaderValueLength = 128
+
+// capturedHeaders exports selected headers of the last /api/status response,
+// e.g. kbn-name or the rate-limit headers of a gateway in front of Kibana.
+// Guarded by the collector mutex.
+type capturedHeaders struct {
+	info   []string
+	gauges []string
+	// values of the last response, keyed by lower-case header name
+	values map[string]string
+
+	infoDesc  *prometheus.Desc
+	gaugeDesc *prometheus.Desc
+}
+
+func newCapturedHeaders(info, gauges []string) *capturedHeaders {
+	return &capturedHeaders{
+		info:   lowerAll(info),
+		gauges: lowerAll(gauges),
+		values: make(map[string]string),
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "response_header", "info"),
+			"Value of a captured header of the last status response; always 1",
+			[]string{"header", "value"}, nil,
+		),
+		gaugeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "response", "header"),
+			"Numeric value of a captured header of the last status response",
+			[]string{"header"}, nil,
+		),
+	}
+}
+
+func lowerAll(names []string) []string {
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	return lower
+}
+
+func (h *capturedHeaders) describe(ch chan<- *prometheus.Desc) {
+	ch <- h.infoDesc
+	ch <- h.gaugeDesc
+}
+
+// reset forgets the headers of the previous response, so a scrape that got
+// no response exports none
+func (h *capturedHeaders) reset() {
+	clear(h.values)
+}
+
+// observe records the captured headers of a response, whatever its status
+func (h *capturedHeaders) observe(resp *http.Response) {
+	for _, names := range [][]string{h.info, h.gauges} {
+		for _, name := range names {
+			if value := resp.Header.Get(name); value != "" {
+				h.values[name] = value
+			}
+		}
+	}
+}
+
+func (h *capturedHeaders) export(ch chan<- prometheus.Metric) {
+	for _, name := range h.info {
+		value, ok := h.values[name]
+		if !ok {
+			continue
+		}
+		if len(value) > maxHeaderValueLength {
+			value = value[:maxHeaderValueLength]
+		}
+		value = strings.ToValidUTF8(value, "")
+		ch <- prometheus.MustNewConstMetric(h.infoDesc, prometheus.GaugeValue, 1, name, value)
+	}
+	for _, name := range h.gauges {
+		value, ok := h.values[name]
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			log.WithFields(log.Fields{"header": name, "value": value}).Debug("Skipping non-numeric response header")
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(h.gaugeDesc, prometheus.GaugeValue, number, name)
+	}
+}
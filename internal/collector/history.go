@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize is the number of scrapes kept per target when
+// Config.HistorySize is zero
+const DefaultHistorySize = 120
+
+// Sample is a condensed record of one scrape
+type Sample struct {
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration_seconds"`
+	Up       bool      `json:"up"`
+	// Error is the stable code of the scrape error, see ErrorCode
+	Error          string  `json:"error,omitempty"`
+	Status         float64 `json:"status"`
+	HeapUsed       float64 `json:"heap_used_bytes"`
+	HeapLimit      float64 `json:"heap_limit_bytes"`
+	EventLoopDelay float64 `json:"event_loop_delay_seconds"`
+	ResponseTime   float64 `json:"response_time_seconds"`
+}
+
+// history is a fixed-size ring buffer of recent samples; it is safe for concurrent use
+type history struct {
+	mutex   sync.RWMutex
+	samples []Sample
+	next    int
+	count   int
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &history{samples: make([]Sample, size)}
+}
+
+func (h *history) add(s Sample) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.samples[h.next] = s
+	h.next = (h.next + 1) % len(h.samples)
+	if h.count < len(h.samples) {
+		h.count++
+	}
+}
+
+// list returns the samples oldest first
+func (h *history) list() []Sample {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	result := make([]Sample, 0, h.count)
+	start := (h.next - h.count + len(h.samples)) % len(h.samples)
+	for i := 0; i < h.count; i++ {
+		result = append(result, h.samples[(start+i)%len(h.samples)])
+	}
+	return result
+}
+
+// latest returns the most recent sample, if any
+func (h *history) latest() (Sample, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if h.count == 0 {
+		return Sample{}, false
+	}
+	return h.samples[(h.next-1+len(h.samples))%len(h.samples)], true
+}
+
+// newSample condenses a scrape result
+func newSample(at time.Time, duration time.Duration, status *KibanaStatus, err error) Sample {
+	s := Sample{Time: at, Duration: duration.Seconds(), Up: err == nil, Error: ErrorCode(err), Status: -1}
+	if status == nil {
+		return s
+	}
+
+	s.Status = overallStatusValue(status.Status.Overall.Level)
+	if mem := status.Metrics.Process.Memory; mem != nil && mem.Heap != nil {
+		s.HeapUsed = float64(mem.Heap.UsedBytes)
+		s.HeapLimit = float64(mem.Heap.SizeLimit)
+	}
+	if status.Metrics.Process.EventLoopDelay != nil {
+		s.EventLoopDelay = *status.Metrics.Process.EventLoopDelay / 1000.0
+	}
+	if rt := status.Metrics.ResponseTimes; rt != nil && rt.Avg != nil {
+		s.ResponseTime = *rt.Avg / 1000.0
+	}
+	return s
+}
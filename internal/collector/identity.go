@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Identity fields accepted by IdentityLabelRules
+const (
+	IdentityName         = "name"
+	IdentityUUID         = "uuid"
+	IdentityVersionMajor = "version_major"
+)
+
+// identityMetrics are the metric families identity labels are added to,
+// including those of targets with a metric prefix
+var identityMetrics = []string{"kibana_*", "*_kibana_*"}
+
+// IdentityLabelRules returns label rules adding what Kibana reports about
+// itself to the series of its target. Each entry is an identity field,
+// labeled kibana_<field>, or field=label to pick the label name. Labels a
+// series already has win over identity labels, so a label set on the target
+// in a targets file overrides what Kibana reports.
+func IdentityLabelRules(entries []string) ([]LabelRule, error) {
+	rules := make([]LabelRule, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		field, label, ok := strings.Cut(entry, "=")
+		if !ok {
+			label = "kibana_" + field
+		}
+		rule := LabelRule{Metrics: identityMetrics, Label: label, Regex: "(.+)", KeepExisting: true}
+		switch field {
+		case IdentityName:
+			rule.Source = LabelSourceName
+		case IdentityUUID:
+			rule.Source = LabelSourceUUID
+		case IdentityVersionMajor:
+			rule.Source, rule.Regex = LabelSourceVersion, `v?(\d+)(?:\..*)?`
+		default:
+			return nil, fmt.Errorf("unknown identity field %q, expected name, uuid or version_major", field)
+		}
+		if seen[label] {
+			return nil, fmt.Errorf("duplicate identity label %q", label)
+		}
+		seen[label] = true
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("identity label %q: %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
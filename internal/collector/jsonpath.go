@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled subset of JSONPath: the root $, child access with
+// .name or ['name'], array indexes [n] and the wildcards .* and [*]
+type jsonPath struct {
+	raw   string
+	steps []pathStep
+}
+
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// pathMatch is a value selected by a path, with the object keys or array
+// indexes matched by each of its wildcards
+type pathMatch struct {
+	value    interface{}
+	captures []string
+}
+
+func compileJSONPath(expr string) (*jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath %q must start with $", expr)
+	}
+	p := &jsonPath{raw: expr}
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("jsonpath %q: empty name", expr)
+			case "*":
+				p.steps = append(p.steps, pathStep{wildcard: true})
+			default:
+				p.steps = append(p.steps, pathStep{key: name})
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %q: unterminated [", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				p.steps = append(p.steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				p.steps = append(p.steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("jsonpath %q: invalid index %q", expr, inner)
+				}
+				p.steps = append(p.steps, pathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// wildcards returns the number of wildcard steps, i.e. captures per match
+func (p *jsonPath) wildcards() int {
+	n := 0
+	for _, step := range p.steps {
+		if step.wildcard {
+			n++
+		}
+	}
+	return n
+}
+
+// eval returns every value of doc selected by the path. Wildcards over
+// objects visit keys in sorted order so that output is stable.
+func (p *jsonPath) eval(doc interface{}) []pathMatch {
+	matches := []pathMatch{{value: doc}}
+	for _, step := range p.steps {
+		var next []pathMatch
+		for _, m := range matches {
+			switch node := m.value.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, m.child(node[k], k))
+					}
+				} else if v, ok := node[step.key]; ok && !step.isIndex {
+					next = append(next, pathMatch{value: v, captures: m.captures})
+				}
+			case []interface{}:
+				if step.wildcard {
+					for i, v := range node {
+						next = append(next, m.child(v, strconv.Itoa(i)))
+					}
+				} else if step.isIndex && step.index < len(node) {
+					next = append(next, pathMatch{value: node[step.index], captures: m.captures})
+				}
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+func (m pathMatch) child(value interface{}, capture string) pathMatch {
+	captures := make([]string, len(m.captures), len(m.captures)+1)
+	copy(captures, m.captures)
+	return pathMatch{value: value, captures: append(captures, capture)}
+}
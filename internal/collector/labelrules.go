@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Payload fields a LabelRule can derive a label from
+const (
+	LabelSourceVersion = "version"
+	LabelSourceName    = "name"
+	LabelSourceUUID    = "uuid"
+)
+
+// LabelRule sets a label on the series of matching metric families at
+// scrape time, like PromQL's label_replace: Regex must match the whole source
+// value, and Replacement, with $1-style references to its groups, becomes
+// the label value. An empty result removes the label; without a match the
+// series is left alone.
+type LabelRule struct {
+	// Metrics are metric family names or path.Match globs, e.g. kibana_status_*
+	Metrics []string `json:"metrics"`
+	// Label is the label to set
+	Label string `json:"label"`
+	// Source is a field Kibana reports about itself: version, name or uuid
+	Source string `json:"source,omitempty"`
+	// SourceLabel is a label of the series itself, used instead of Source
+	SourceLabel string `json:"source_label,omitempty"`
+	// Regex defaults to (.*) and Replacement to $1
+	Regex       string `json:"regex,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	// KeepExisting leaves series alone that already have Label, e.g. from
+	// the target's labels, instead of replacing it
+	KeepExisting bool `json:"keep_existing,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// labelRulesFile is the format of a label rules file
+type labelRulesFile struct {
+	Rules []LabelRule `json:"rules"`
+}
+
+// LoadLabelRules reads and validates a JSON label rules file
+func LoadLabelRules(file string) ([]LabelRule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config labelRulesFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	for i := range config.Rules {
+		if err := config.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", file, i+1, err)
+		}
+	}
+	return config.Rules, nil
+}
+
+func (r *LabelRule) compile() error {
+	if len(r.Metrics) == 0 {
+		return fmt.Errorf("no metrics")
+	}
+	for _, pattern := range r.Metrics {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid metrics pattern %q: %w", pattern, err)
+		}
+	}
+	if !labelNameRE.MatchString(r.Label) || strings.HasPrefix(r.Label, "__") {
+		return fmt.Errorf("invalid label name %q", r.Label)
+	}
+	switch {
+	case r.Source != "" && r.SourceLabel != "":
+		return fmt.Errorf("source and source_label are mutually exclusive")
+	case r.SourceLabel != "":
+		if !labelNameRE.MatchString(r.SourceLabel) {
+			return fmt.Errorf("invalid source_label %q", r.SourceLabel)
+		}
+	case r.Source != LabelSourceVersion && r.Source != LabelSourceName && r.Source != LabelSourceUUID:
+		return fmt.Errorf("invalid source %q, expected %s, %s or %s", r.Source, LabelSourceVersion, LabelSourceName, LabelSourceUUID)
+	}
+	if r.Regex == "" {
+		r.Regex = "(.*)"
+	}
+	if r.Replacement == "" {
+		r.Replacement = "$1"
+	}
+	var err error
+	if r.regex, err = regexp.Compile("^(?:" + r.Regex + ")$"); err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+	return nil
+}
+
+func (r *LabelRule) matches(family string) bool {
+	for _, pattern := range r.Metrics {
+		if ok, _ := path.Match(pattern, family); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the rule's label on m, with state being the scrape state of the
+// target m belongs to, if known
+func (r *LabelRule) apply(m *dto.Metric, state *ScrapeState) {
+	if r.KeepExisting && labelValue(m, r.Label) != "" {
+		return
+	}
+	var source string
+	switch {
+	case r.SourceLabel != "":
+		source = labelValue(m, r.SourceLabel)
+	case state == nil:
+		return
+	case r.Source == LabelSourceVersion:
+		source = state.Version
+	case r.Source == LabelSourceName:
+		source = state.Name
+	case r.Source == LabelSourceUUID:
+		source = state.UUID
+	}
+	match := r.regex.FindStringSubmatchIndex(source)
+	if match == nil {
+		return
+	}
+	setLabel(m, r.Label, string(r.regex.ExpandString(nil, r.Replacement, source, match)))
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, pair := range m.Label {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+	return ""
+}
+
+// setLabel sets or, with an empty value, removes a label, keeping the labels
+// sorted by name
+func setLabel(m *dto.Metric, name, value string) {
+	for i, pair := range m.Label {
+		switch {
+		case pair.GetName() == name && value == "":
+			m.Label = append(m.Label[:i], m.Label[i+1:]...)
+			return
+		case pair.GetName() == name:
+			pair.Value = &value
+			return
+		case pair.GetName() > name:
+			if value != "" {
+				m.Label = append(m.Label[:i], append([]*dto.LabelPair{{Name: &name, Value: &value}}, m.Label[i:]...)...)
+			}
+			return
+		}
+	}
+	if value != "" {
+		m.Label = append(m.Label, &dto.LabelPair{Name: &name, Value: &value})
+	}
+}
+
+// labelRulesGatherer applies label rules to the series of a gatherer
+type labelRulesGatherer struct {
+	gatherer prometheus.Gatherer
+	targets  *Targets
+	rules    []LabelRule
+}
+
+// WithLabelRules applies label rules to the series g gathers, if there are
+// any. Series belong to the target whose labels they carry; rules with a
+// Source skip series of no target.
+func (t *Targets) WithLabelRules(g prometheus.Gatherer, rules []LabelRule) prometheus.Gatherer {
+	if len(rules) == 0 {
+		return g
+	}
+	return labelRulesGatherer{gatherer: g, targets: t, rules: rules}
+}
+
+type targetState struct {
+	labels map[string]string
+	state  ScrapeState
+}
+
+func (g labelRulesGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+
+	g.targets.mutex.RLock()
+	targets := make([]targetState, 0, len(g.targets.targets))
+	for _, managed := range g.targets.targets {
+		targets = append(targets, targetState{labels: managed.target.Labels, state: managed.collector.tracker.snapshot()})
+	}
+	g.targets.mutex.RUnlock()
+
+	for _, family := range families {
+		for i := range g.rules {
+			rule := &g.rules[i]
+			if !rule.matches(family.GetName()) {
+				continue
+			}
+			for _, m := range family.Metric {
+				rule.apply(m, ownerState(targets, m))
+			}
+		}
+	}
+	return families, err
+}
+
+// ownerState returns the scrape state of the target whose labels m carries
+func ownerState(targets []targetState, m *dto.Metric) *ScrapeState {
+	for i := range targets {
+		owned := true
+		for name, value := range targets[i].labels {
+			if labelValue(m, name) != value {
+				owned = false
+				break
+			}
+		}
+		if owned {
+			return &targets[i].state
+		}
+	}
+	return nil
+}
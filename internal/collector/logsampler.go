@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// failureLogSampler rate-limits scrape failure logs while Kibana is down.
+// The first failure is always logged, further failures at most once per
+// interval, and a summary is logged when scraping recovers.
+type failureLogSampler struct {
+	target   string
+	interval time.Duration
+
+	failing    bool
+	firstSeen  time.Time
+	lastLogged time.Time
+	failures   int
+	suppressed int
+}
+
+func newFailureLogSampler(target string, interval time.Duration) *failureLogSampler {
+	return &failureLogSampler{target: target, interval: interval}
+}
+
+// Failure records a failed scrape and logs it if it is not sampled out
+func (s *failureLogSampler) Failure(err error) {
+	now := time.Now()
+	s.failures++
+
+	if !s.failing {
+		s.failing = true
+		s.firstSeen = now
+	} else if s.interval > 0 && now.Sub(s.lastLogged) < s.interval {
+		s.suppressed++
+		return
+	}
+
+	entry := log.WithError(err).WithFields(log.Fields{
+		"target":     s.target,
+		"error_code": ErrorCode(err),
+	})
+	if s.suppressed > 0 {
+		entry = entry.WithFields(log.Fields{
+			"consecutive_failures": s.failures,
+			"suppressed":           s.suppressed,
+			"failing_since":        s.firstSeen.Format(time.RFC3339),
+		})
+	}
+	entry.Error("Failed to scrape Kibana")
+
+	s.lastLogged = now
+	s.suppressed = 0
+}
+
+// Success records a successful scrape and logs a recovery summary after an outage
+func (s *failureLogSampler) Success() {
+	if !s.failing {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"target":         s.target,
+		"failed_scrapes": s.failures,
+		"outage":         time.Since(s.firstSeen).Round(time.Second).String(),
+	}).Info("Kibana scrape recovered")
+
+	s.failing = false
+	s.failures = 0
+	s.suppressed = 0
+}
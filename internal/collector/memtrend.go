@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxMemorySamples bounds the trend history regardless of the scrape interval
+const maxMemorySamples = 1000
+
+type memorySample struct {
+	at       time.Time
+	heap     float64
+	resident float64
+}
+
+// memoryTrend fits a line through the heap and RSS samples of the last
+// window, so that slow leaks show up as a steady growth rate without
+// long-range queries over raw gauges that drop on every restart. The history
+// starts over when Kibana restarts. Guarded by the collector mutex.
+type memoryTrend struct {
+	window   time.Duration
+	samples  []memorySample
+	restarts uint64
+	// hasResident is false when Kibana does not report the RSS
+	hasResident bool
+
+	heapDesc     *prometheus.Desc
+	residentDesc *prometheus.Desc
+}
+
+func newMemoryTrend(window time.Duration) *memoryTrend {
+	return &memoryTrend{
+		window: window,
+		heapDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "heap", "growth_bytes_per_hour"),
+			"Trend of the used heap over the memory trend window, by linear regression since the last restart",
+			nil, nil,
+		),
+		residentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "memory_resident_set", "growth_bytes_per_hour"),
+			"Trend of the resident set size over the memory trend window, by linear regression since the last restart",
+			nil, nil,
+		),
+	}
+}
+
+func (m *memoryTrend) describe(ch chan<- *prometheus.Desc) {
+	ch <- m.heapDesc
+	ch <- m.residentDesc
+}
+
+// observe adds a sample taken at Kibana's collected_at time; repeated scrapes
+// of the same sample are ignored
+func (m *memoryTrend) observe(collectedAt string, heap float64, resident *int64, restarts uint64) {
+	at, err := time.Parse(time.RFC3339, collectedAt)
+	if err != nil {
+		at = time.Now()
+	}
+	if restarts != m.restarts {
+		m.samples, m.restarts = m.samples[:0], restarts
+	}
+	if n := len(m.samples); n > 0 && !at.After(m.samples[n-1].at) {
+		return
+	}
+
+	sample := memorySample{at: at, heap: heap}
+	if m.hasResident = resident != nil; m.hasResident {
+		sample.resident = float64(*resident)
+	}
+	m.samples = append(m.samples, sample)
+	drop := 0
+	for drop < len(m.samples) && (at.Sub(m.samples[drop].at) > m.window || len(m.samples)-drop > maxMemorySamples) {
+		drop++
+	}
+	m.samples = append(m.samples[:0], m.samples[drop:]...)
+}
+
+// export reports the growth rates once the samples span half of the window,
+// as shorter histories mostly show garbage collection cycles
+func (m *memoryTrend) export(ch chan<- prometheus.Metric) {
+	n := len(m.samples)
+	if n < 3 || m.samples[n-1].at.Sub(m.samples[0].at) < m.window/2 {
+		return
+	}
+	heap, resident := m.slopes()
+	ch <- prometheus.MustNewConstMetric(m.heapDesc, prometheus.GaugeValue, heap*3600)
+	if m.hasResident {
+		ch <- prometheus.MustNewConstMetric(m.residentDesc, prometheus.GaugeValue, resident*3600)
+	}
+}
+
+// slopes returns the least squares slopes of heap and RSS in bytes per second
+func (m *memoryTrend) slopes() (heap, resident float64) {
+	origin := m.samples[0].at
+	var sumT, sumTT, sumH, sumTH, sumR, sumTR float64
+	for _, s := range m.samples {
+		t := s.at.Sub(origin).Seconds()
+		sumT += t
+		sumTT += t * t
+		sumH += s.heap
+		sumTH += t * s.heap
+		sumR += s.resident
+		sumTR += t * s.resident
+	}
+	n := float64(len(m.samples))
+	denominator := n*sumTT - sumT*sumT
+	if denominator == 0 {
+		return 0, 0
+	}
+	return (n*sumTH - sumT*sumH) / denominator, (n*sumTR - sumT*sumR) / denominator
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// OpenSearch Dashboards plugins the opensearch_plugins collector can read
+const (
+	OpenSearchAlerting         = "alerting"
+	OpenSearchAnomalyDetection = "anomaly_detection"
+	OpenSearchIndexManagement  = "index_management"
+)
+
+// osdListResponse is the envelope of the list APIs of the OpenSearch
+// Dashboards plugins. Each plugin names its total differently; the lists
+// themselves are not needed, so they are requested with size=0.
+type osdListResponse struct {
+	OK            *bool       `json:"ok"`
+	Error         interface{} `json:"error"`
+	TotalMonitors *float64    `json:"totalMonitors"`
+	TotalAlerts   *float64    `json:"totalAlerts"`
+	Response      struct {
+		TotalDetectors      *float64 `json:"totalDetectors"`
+		TotalPolicies       *float64 `json:"totalPolicies"`
+		TotalManagedIndices *float64 `json:"totalManagedIndices"`
+	} `json:"response"`
+}
+
+// osdPluginCount is a count exported for an OpenSearch Dashboards plugin
+type osdPluginCount struct {
+	kind     string
+	endpoint string
+	total    func(*osdListResponse) *float64
+}
+
+var osdPluginCounts = map[string][]osdPluginCount{
+	OpenSearchAlerting: {
+		{"monitors", "/api/alerting/monitors?from=0&size=0&search=&sortField=name&sortDirection=desc&state=all",
+			func(r *osdListResponse) *float64 { return r.TotalMonitors }},
+		{"active_alerts", "/api/alerting/alerts?from=0&size=0&search=&sortField=start_time&sortDirection=desc&severityLevel=ALL&alertState=ACTIVE",
+			func(r *osdListResponse) *float64 { return r.TotalAlerts }},
+	},
+	OpenSearchAnomalyDetection: {
+		{"detectors", "/api/anomaly_detectors/detectors?from=0&size=0&search=&indices=&sortDirection=desc&sortField=name",
+			func(r *osdListResponse) *float64 { return r.Response.TotalDetectors }},
+	},
+	OpenSearchIndexManagement: {
+		{"policies", "/api/ism/policies?from=0&size=0&search=&sortField=id&sortDirection=desc",
+			func(r *osdListResponse) *float64 { return r.Response.TotalPolicies }},
+		{"managed_indices", "/api/ism/managedIndices?from=0&size=0&search=&sortField=index&sortDirection=desc&showDataStreams=true",
+			func(r *osdListResponse) *float64 { return r.Response.TotalManagedIndices }},
+	},
+}
+
+// ValidOpenSearchPlugin reports whether name is a plugin the
+// opensearch_plugins collector can read
+func ValidOpenSearchPlugin(name string) bool {
+	_, ok := osdPluginCounts[name]
+	return ok
+}
+
+type openSearchDescs struct {
+	up      *prometheus.Desc
+	objects *prometheus.Desc
+}
+
+func newOpenSearchDescs() openSearchDescs {
+	return openSearchDescs{
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "opensearch_plugin", "up"),
+			"Whether all APIs of an OpenSearch Dashboards plugin answered the last scrape",
+			[]string{"plugin"}, nil,
+		),
+		objects: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "opensearch_plugin", "objects"),
+			"Objects managed by an OpenSearch Dashboards plugin, by kind, e.g. alerting monitors",
+			[]string{"plugin", "kind"}, nil,
+		),
+	}
+}
+
+func (d openSearchDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.up
+	ch <- d.objects
+}
+
+// collectOpenSearchPlugins exports the object counts of the configured
+// OpenSearch Dashboards plugins, which have no metrics endpoint of their own
+func (c *KibanaCollector) collectOpenSearchPlugins(ctx context.Context, ch chan<- prometheus.Metric) {
+	for _, plugin := range c.config.OpenSearchPlugins {
+		up := 1.0
+		for _, count := range osdPluginCounts[plugin] {
+			total, ok := c.getOpenSearchCount(ctx, count)
+			if !ok {
+				up = 0
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.openSearch.objects, prometheus.GaugeValue, total, plugin, count.kind)
+		}
+		ch <- prometheus.MustNewConstMetric(c.openSearch.up, prometheus.GaugeValue, up, plugin)
+	}
+}
+
+// getOpenSearchCount fetches a plugin's list API, skipping it while it is
+// forbidden, and reports whether it returned a total
+func (c *KibanaCollector) getOpenSearchCount(ctx context.Context, count osdPluginCount) (float64, bool) {
+	if c.forbidden.skip(CollectorOpenSearchPlugins, count.endpoint) {
+		return 0, false
+	}
+	var response osdListResponse
+	err := c.getJSON(ctx, count.endpoint, &response)
+	if err == nil {
+		switch total := count.total(&response); {
+		case response.OK != nil && !*response.OK:
+			err = newScrapeError(ErrHTTPStatus, "plugin reported an error: %v", response.Error)
+		case total == nil:
+			err = newScrapeError(ErrSchema, "no total in response")
+		default:
+			return *total, true
+		}
+	}
+	if !c.forbidden.observe(c.config.KibanaURL, CollectorOpenSearchPlugins, count.endpoint, err) {
+		log.WithError(err).WithFields(log.Fields{
+			"target":     c.config.KibanaURL,
+			"endpoint":   count.endpoint,
+			"error_code": ErrorCode(err),
+		}).Warn("Failed to scrape OpenSearch Dashboards plugin")
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapePhases are the durations of the steps of one Kibana request. Steps
+// skipped on a reused connection stay zero.
+type scrapePhases struct {
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	server    time.Duration
+	transfer  time.Duration
+	reused    bool
+	firstByte time.Time
+}
+
+type phaseDescs struct {
+	duration *prometheus.Desc
+	reused   *prometheus.Desc
+}
+
+func newPhaseDescs() phaseDescs {
+	return phaseDescs{
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "scrape_phase_duration_seconds"),
+			"Duration of each phase of the last Kibana status request: dns, connect, tls, server (time to first byte) and transfer",
+			[]string{"phase"}, nil,
+		),
+		reused: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "connection_reused"),
+			"Whether the last Kibana status request reused a kept-alive connection",
+			nil, nil,
+		),
+	}
+}
+
+func (d phaseDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.duration
+	ch <- d.reused
+}
+
+func (d phaseDescs) export(ch chan<- prometheus.Metric, p scrapePhases) {
+	p.each(func(name string, duration time.Duration) {
+		ch <- prometheus.MustNewConstMetric(d.duration, prometheus.GaugeValue, duration.Seconds(), name)
+	})
+	reused := 0.0
+	if p.reused {
+		reused = 1
+	}
+	ch <- prometheus.MustNewConstMetric(d.reused, prometheus.GaugeValue, reused)
+}
+
+// each calls f for every phase in request order
+func (p scrapePhases) each(f func(name string, duration time.Duration)) {
+	f("dns", p.dns)
+	f("connect", p.connect)
+	f("tls", p.tls)
+	f("server", p.server)
+	f("transfer", p.transfer)
+}
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultPluginStatusLimit caps plugin status series per target
+const DefaultPluginStatusLimit = 100
+
+// pluginStatusCollector names the plugin status series in per-collector metrics
+const pluginStatusCollector = "plugin_status"
+
+// PluginStatusConfig enables per-plugin status series. Include and Exclude
+// are glob patterns matched against plugin names; an empty Include keeps
+// every plugin. At most Limit plugins are exported, in name order.
+//
+// With Unhealthy set, only the plugins that are not available are exported,
+// at most Unhealthy of them, most severe first, along with the number of
+// plugins per level. This keeps installs with hundreds of plugins cheap.
+type PluginStatusConfig struct {
+	Enabled   bool
+	Include   []string
+	Exclude   []string
+	Limit     int
+	Unhealthy int
+}
+
+// Validate checks the glob patterns
+func (p PluginStatusConfig) Validate() error {
+	for _, pattern := range slices.Concat(p.Include, p.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid plugin pattern %q: %w", pattern, err)
+		}
+	}
+	if p.Limit < 0 {
+		return fmt.Errorf("invalid plugin status limit %d", p.Limit)
+	}
+	if p.Unhealthy < 0 {
+		return fmt.Errorf("invalid unhealthy plugin count %d", p.Unhealthy)
+	}
+	return nil
+}
+
+func (p PluginStatusConfig) selects(name string) bool {
+	if len(p.Include) > 0 && !matchAny(p.Include, name) {
+		return false
+	}
+	return !matchAny(p.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginStatus exports the selected plugin statuses and counts the series
+// dropped by the limit. Guarded by the collector mutex.
+type pluginStatus struct {
+	config  PluginStatusConfig
+	dropped uint64
+	warned  bool
+	// series sent by the last export
+	series int
+
+	statusDesc  *prometheus.Desc
+	droppedDesc *prometheus.Desc
+	levelsDesc  *prometheus.Desc
+}
+
+// pluginLevels orders the plugin levels from most to least severe
+var pluginLevels = []string{"critical", "unavailable", "degraded", "available"}
+
+func levelSeverity(level string) int {
+	if i := slices.Index(pluginLevels, level); i >= 0 {
+		return i
+	}
+	// Unknown levels sort between degraded and available
+	return len(pluginLevels) - 1
+}
+
+func newPluginStatus(config PluginStatusConfig) *pluginStatus {
+	if config.Limit == 0 {
+		config.Limit = DefaultPluginStatusLimit
+	}
+	return &pluginStatus{
+		config: config,
+		statusDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "plugin"),
+			"Kibana plugin status (1=available, 0=unavailable)",
+			[]string{"name"}, nil,
+		),
+		droppedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "series_dropped_total"),
+			"Series not exported because a collector exceeded its series limit",
+			[]string{"collector"}, nil,
+		),
+		levelsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "plugins"),
+			"Kibana plugins by status level",
+			[]string{"level"}, nil,
+		),
+	}
+}
+
+func (p *pluginStatus) describe(ch chan<- *prometheus.Desc) {
+	ch <- p.statusDesc
+	ch <- p.droppedDesc
+	if p.config.Unhealthy > 0 {
+		ch <- p.levelsDesc
+	}
+}
+
+func (p *pluginStatus) export(ch chan<- prometheus.Metric, url string, plugins map[string]*ServiceStatus) {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		if p.config.selects(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if p.config.Unhealthy > 0 {
+		p.exportUnhealthy(ch, plugins, names)
+		return
+	}
+	if len(names) > p.config.Limit {
+		p.dropped += uint64(len(names) - p.config.Limit)
+		if !p.warned {
+			p.warned = true
+			log.WithFields(log.Fields{"kibana_url": url, "plugins": len(names), "limit": p.config.Limit}).
+				Warn("Too many plugin status series, dropping the rest; narrow them down with --plugin-status-include/--plugin-status-exclude")
+		}
+		names = names[:p.config.Limit]
+	}
+
+	for _, name := range names {
+		value := 0.0
+		if plugins[name].Level == "available" {
+			value = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(p.statusDesc, prometheus.GaugeValue, value, name)
+	}
+	ch <- prometheus.MustNewConstMetric(p.droppedDesc, prometheus.CounterValue, float64(p.dropped), pluginStatusCollector)
+	p.series = len(names) + 1
+}
+
+// exportUnhealthy exports the most severe of the selected plugins that are
+// not available, and the number of selected plugins per level
+func (p *pluginStatus) exportUnhealthy(ch chan<- prometheus.Metric, plugins map[string]*ServiceStatus, names []string) {
+	levels := map[string]int{}
+	for _, level := range pluginLevels {
+		levels[level] = 0
+	}
+	var unhealthy []string
+	for _, name := range names {
+		level := plugins[name].Level
+		levels[level]++
+		if level != "available" {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	// names is sorted, so plugins of the same level stay in name order
+	slices.SortStableFunc(unhealthy, func(a, b string) int {
+		return levelSeverity(plugins[a].Level) - levelSeverity(plugins[b].Level)
+	})
+	if len(unhealthy) > p.config.Unhealthy {
+		p.dropped += uint64(len(unhealthy) - p.config.Unhealthy)
+		unhealthy = unhealthy[:p.config.Unhealthy]
+	}
+
+	for _, name := range unhealthy {
+		ch <- prometheus.MustNewConstMetric(p.statusDesc, prometheus.GaugeValue, 0, name)
+	}
+	for _, level := range slices.Sorted(maps.Keys(levels)) {
+		ch <- prometheus.MustNewConstMetric(p.levelsDesc, prometheus.GaugeValue, float64(levels[level]), level)
+	}
+	ch <- prometheus.MustNewConstMetric(p.droppedDesc, prometheus.CounterValue, float64(p.dropped), pluginStatusCollector)
+	p.series = len(unhealthy) + len(levels) + 1
+}
@@ -0,0 +1,32 @@
+package collector
+
+import "fmt"
+
+// Privilege is the Kibana feature privilege an optional collector needs
+type Privilege struct {
+	// Feature is the Kibana feature ID used in role definitions
+	Feature string
+	// Level is the feature privilege, e.g. read
+	Level string
+}
+
+func (p Privilege) String() string {
+	return fmt.Sprintf("kibana feature %s: %s", p.Feature, p.Level)
+}
+
+// requiredPrivileges maps collectors to the Kibana feature privileges they need
+var requiredPrivileges = map[string]Privilege{
+	SpaceSavedObjects:  {Feature: "savedObjectsManagement", Level: "read"},
+	SpaceAlertingRules: {Feature: "stackAlerts", Level: "read"},
+	SpaceDataViews:     {Feature: "indexPatterns", Level: "read"},
+
+	CollectorUISettings:        {Feature: "advancedSettings", Level: "read"},
+	CollectorSavedObjectsProbe: {Feature: "savedObjectsManagement", Level: "read"},
+}
+
+// RequiredPrivilege returns the privilege a collector needs, if it needs one
+// beyond access to the space
+func RequiredPrivilege(collector string) (Privilege, bool) {
+	p, ok := requiredPrivileges[collector]
+	return p, ok
+}
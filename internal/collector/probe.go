@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const savedObjectsProbeEndpoint = "/api/saved_objects/_find"
+
+type savedObjectsProbeDescs struct {
+	success  *prometheus.Desc
+	duration *prometheus.Desc
+}
+
+func newSavedObjectsProbeDescs() savedObjectsProbeDescs {
+	return savedObjectsProbeDescs{
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "saved_objects_success"),
+			"Whether the last synthetic saved object search through Kibana succeeded",
+			[]string{"type"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "saved_objects_duration_seconds"),
+			"Duration of the last synthetic saved object search through Kibana",
+			[]string{"type"}, nil,
+		),
+	}
+}
+
+func (d savedObjectsProbeDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.success
+	ch <- d.duration
+}
+
+// collectSavedObjectsProbe searches for one saved object of the configured
+// type. Unlike /api/status, which Kibana answers from its own view of its
+// health, the search has to go through Elasticsearch, so it checks the data
+// path end to end.
+func (c *KibanaCollector) collectSavedObjectsProbe(ctx context.Context, ch chan<- prometheus.Metric) {
+	objectType := c.config.SavedObjectsProbe
+	if c.forbidden.skip(CollectorSavedObjectsProbe, savedObjectsProbeEndpoint) {
+		return
+	}
+	start := time.Now()
+	var found findResponse
+	err := c.getJSON(ctx, savedObjectsProbeEndpoint+"?per_page=1&type="+url.QueryEscape(objectType), &found)
+	duration := time.Since(start)
+
+	success := 1.0
+	if err != nil {
+		success = 0
+		if !c.forbidden.observe(c.config.KibanaURL, CollectorSavedObjectsProbe, savedObjectsProbeEndpoint, err) {
+			log.WithError(err).WithFields(log.Fields{
+				"target":     c.config.KibanaURL,
+				"type":       objectType,
+				"error_code": ErrorCode(err),
+			}).Warn("Saved object probe failed")
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.savedObjectsProbe.success, prometheus.GaugeValue, success, objectType)
+	ch <- prometheus.MustNewConstMetric(c.savedObjectsProbe.duration, prometheus.GaugeValue, duration.Seconds(), objectType)
+}
+
+type frontendProbeDescs struct {
+	up       *prometheus.Desc
+	duration *prometheus.Desc
+}
+
+func newFrontendProbeDescs() frontendProbeDescs {
+	return frontendProbeDescs{
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "frontend", "up"),
+			"Whether the Kibana login page was served with the expected status and content",
+			nil, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "frontend", "duration_seconds"),
+			"Duration of the last request for the Kibana login page, redirects included",
+			nil, nil,
+		),
+	}
+}
+
+func (d frontendProbeDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.up
+	ch <- d.duration
+}
+
+// collectFrontendProbe requests the configured page without credentials, the
+// way a browser would, and checks its status and content. A proxy in front
+// of Kibana can break the UI while the API still answers.
+func (c *KibanaCollector) collectFrontendProbe(ctx context.Context, ch chan<- prometheus.Metric) {
+	start := time.Now()
+	err := c.getFrontendPage(ctx)
+	duration := time.Since(start)
+
+	up := 1.0
+	if err != nil {
+		up = 0
+		log.WithError(err).WithFields(log.Fields{
+			"target":     c.config.KibanaURL,
+			"path":       c.config.FrontendProbe,
+			"error_code": ErrorCode(err),
+		}).Warn("Frontend probe failed")
+	}
+	ch <- prometheus.MustNewConstMetric(c.frontendProbe.up, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(c.frontendProbe.duration, prometheus.GaugeValue, duration.Seconds())
+}
+
+// frontendProbeBodyLimit bounds how much of the page is searched for
+// FrontendProbeContains
+const frontendProbeBodyLimit = 1 << 20
+
+func (c *KibanaCollector) getFrontendPage(ctx context.Context) (err error) {
+	ctx, span := c.startRequestSpan(ctx, c.config.FrontendProbe)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.KibanaURL+c.config.FrontendProbe, nil)
+	if err != nil {
+		return &ScrapeError{Kind: ErrRequest, Err: err}
+	}
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	if traceParent := span.TraceParent(); traceParent != "" {
+		req.Header.Set("traceparent", traceParent)
+	}
+
+	if err := c.throttle.check(); err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	defer resp.Body.Close()
+	c.throttle.observe(resp)
+	span.SetAttribute("http.status_code", resp.StatusCode)
+
+	expected := c.config.FrontendProbeStatus
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	if resp.StatusCode != expected {
+		return &ScrapeError{
+			Kind:       ErrHTTPStatus,
+			Err:        fmt.Errorf("got HTTP %d, expected %d", resp.StatusCode, expected),
+			StatusCode: resp.StatusCode,
+		}
+	}
+	if c.config.FrontendProbeContains == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, frontendProbeBodyLimit))
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	if !strings.Contains(string(body), c.config.FrontendProbeContains) {
+		return newScrapeError(ErrSchema, "page does not contain %q", c.config.FrontendProbeContains)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package collector
+
+// requestRateSmoothing is the weight of the newest sample in the moving average
+const requestRateSmoothing = 0.3
+
+// requestRate derives a smoothed requests-per-second figure from Kibana
+// samples. Kibana counts requests per collection interval and starts again
+// at zero for the next, so each sample's total is divided by its interval.
+// Samples are told apart by collected_at, so repeated scrapes of the same
+// sample do not skew the average.
+type requestRate struct {
+	lastAt string
+	rate   float64
+	valid  bool
+}
+
+// update adds a sample and returns the current rate, or false until a sample
+// with a collection interval has been seen
+func (r *requestRate) update(collectedAt string, intervalMillis *float64, total float64) (float64, bool) {
+	if intervalMillis == nil || *intervalMillis <= 0 || (collectedAt != "" && collectedAt == r.lastAt) {
+		return r.rate, r.valid
+	}
+	r.lastAt = collectedAt
+
+	current := total / (*intervalMillis / 1000)
+	if r.valid {
+		r.rate = requestRateSmoothing*current + (1-requestRateSmoothing)*r.rate
+	} else {
+		r.rate, r.valid = current, true
+	}
+	return r.rate, true
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultRecordLimit is the number of recordings kept per API path
+const defaultRecordLimit = 100
+
+var fixtureNameReplacer = strings.NewReplacer("/", "_", "?", "_", "&", "_", "=", "_", ":", "_")
+
+// fixtureDir is where responses for req are recorded: <dir>/<host>/<path and query>
+func fixtureDir(dir string, req *http.Request) string {
+	name := strings.TrimPrefix(req.URL.RequestURI(), "/")
+	return filepath.Join(dir, fixtureNameReplacer.Replace(req.URL.Host), fixtureNameReplacer.Replace(name))
+}
+
+// recordingTransport saves every raw Kibana response, headers included, so
+// that scrapes can be replayed later
+type recordingTransport struct {
+	next  http.RoundTripper
+	dir   string
+	limit int
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	// Cookies may carry session tokens, keep them out of fixtures
+	cookies := resp.Header.Values("Set-Cookie")
+	resp.Header.Del("Set-Cookie")
+	dump, err := httputil.DumpResponse(resp, true)
+	for _, cookie := range cookies {
+		resp.Header.Add("Set-Cookie", cookie)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.save(req, dump); err != nil {
+		log.WithError(err).WithField("url", req.URL.Redacted()).Warn("Failed to record Kibana response")
+	}
+	return resp, nil
+}
+
+func (t *recordingTransport) save(req *http.Request, dump []byte) error {
+	dir := fixtureDir(t.dir, req)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, strconv.FormatInt(time.Now().UnixNano(), 10)+".http")
+	if err := os.WriteFile(name, dump, 0o644); err != nil {
+		return err
+	}
+
+	files, err := fixtureFiles(dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > t.limit {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// fixtureFiles returns the recordings in dir, oldest first
+func fixtureFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.http"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// replayTransport answers requests from recorded responses instead of Kibana.
+// Recordings of a path are served in order and start over when exhausted.
+type replayTransport struct {
+	dir string
+
+	mutex sync.Mutex
+	next  map[string]int
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dir := fixtureDir(t.dir, req)
+	files, err := fixtureFiles(dir)
+	if err == nil && len(files) == 0 {
+		// A fixture set recorded against a single host replays for any URL
+		if hosts, _ := os.ReadDir(t.dir); len(hosts) == 1 {
+			dir = filepath.Join(t.dir, hosts[0].Name(), filepath.Base(dir))
+			files, err = fixtureFiles(dir)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s in %s", req.URL.RequestURI(), t.dir)
+	}
+
+	t.mutex.Lock()
+	i := t.next[dir] % len(files)
+	t.next[dir] = i + 1
+	t.mutex.Unlock()
+
+	data, err := os.ReadFile(files[i])
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", files[i], err)
+	}
+	return resp, nil
+}
+
+// wrapRecording adds recording or replay to the transport if configured
+func wrapRecording(transport http.RoundTripper, config TransportConfig) http.RoundTripper {
+	if config.ReplayDir != "" {
+		var replay http.RoundTripper = &replayTransport{dir: config.ReplayDir, next: make(map[string]int)}
+		if config.Chaos.enabled() {
+			replay = &chaosTransport{next: replay, config: config.Chaos}
+		}
+		return replay
+	}
+	if config.RecordDir != "" {
+		limit := config.RecordLimit
+		if limit <= 0 {
+			limit = defaultRecordLimit
+		}
+		return &recordingTransport{next: transport, dir: config.RecordDir, limit: limit}
+	}
+	return transport
+}
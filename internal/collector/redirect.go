@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// RedirectPolicy controls how the Kibana client handles redirects
+type RedirectPolicy struct {
+	// Disable reports redirects as errors instead of following them
+	Disable bool
+	// Max is the number of redirects followed before giving up (10 if zero)
+	Max int
+	// CrossOriginCredentials re-sends basic auth when redirected to another host.
+	// By default net/http drops the Authorization header on cross-origin redirects.
+	CrossOriginCredentials bool
+}
+
+// checkRedirect implements http.Client.CheckRedirect
+func (c *KibanaCollector) checkRedirect(req *http.Request, via []*http.Request) error {
+	policy := c.config.Redirects
+	if policy.Disable {
+		return http.ErrUseLastResponse
+	}
+	max := policy.Max
+	if max <= 0 {
+		max = 10
+	}
+	if len(via) > max {
+		return &ScrapeError{Kind: ErrHTTPStatus, Err: fmt.Errorf("stopped after %d redirects, last to %s", max, req.URL.Redacted())}
+	}
+	if auth := c.auth.Load(); policy.CrossOriginCredentials && req.URL.Host != via[0].URL.Host && auth.err == nil {
+		if err := auth.provider.Apply(req); err != nil {
+			return &ScrapeError{Kind: ErrRequest, Err: fmt.Errorf("applying credentials: %w", err)}
+		}
+	}
+	return nil
+}
+
+// redirectedAway reports whether resp is not Kibana's JSON API response because
+// a redirect was followed to another page, typically an SSO login form
+func redirectedAway(resp *http.Response, original *url.URL) bool {
+	if resp.Request == nil || resp.Request.URL.String() == original.String() {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType != "application/json"
+}
+
+// classifyRedirect reports a redirect away from the API as an authentication
+// error, since a proxy sending the exporter to a login page is the common cause
+func classifyRedirect(statusCode int, location string) error {
+	return &ScrapeError{
+		Kind:       ErrAuth,
+		Err:        fmt.Errorf("kibana redirected to %s, likely a login page; check credentials or scrape Kibana behind the proxy", location),
+		StatusCode: statusCode,
+	}
+}
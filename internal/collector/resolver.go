@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dnsResolver caches lookups of the Kibana host for a fixed TTL so that
+// exporters behind changing load balancer IPs re-resolve on a known schedule
+type dnsResolver struct {
+	ttl      time.Duration
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	onChange func()
+
+	mutex sync.Mutex
+	cache map[string]dnsEntry
+
+	changes  atomic.Uint64
+	failures atomic.Uint64
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSResolver(ttl time.Duration) *dnsResolver {
+	return &dnsResolver{
+		ttl:    ttl,
+		lookup: net.DefaultResolver.LookupIPAddr,
+		cache:  make(map[string]dnsEntry),
+	}
+}
+
+// resolve returns the cached addresses of host, looking them up again once the TTL expires.
+// If a refresh fails, the stale addresses are used until the next attempt.
+func (r *dnsResolver) resolve(ctx context.Context, host string) ([]string, error) {
+	r.mutex.Lock()
+	entry, cached := r.cache[host]
+	r.mutex.Unlock()
+
+	if cached && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	ips, err := r.lookup(ctx, host)
+	if err != nil || len(ips) == 0 {
+		r.failures.Add(1)
+		if cached {
+			log.WithError(err).WithField("host", host).Warn("DNS re-resolution failed, using cached addresses")
+			return entry.addrs, nil
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	sort.Strings(addrs)
+
+	r.mutex.Lock()
+	r.cache[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(r.ttl)}
+	r.mutex.Unlock()
+
+	if cached && strings.Join(addrs, ",") != strings.Join(entry.addrs, ",") {
+		r.changes.Add(1)
+		log.WithFields(log.Fields{"host": host, "old": entry.addrs, "new": addrs}).Info("Kibana host resolved to new addresses")
+		if r.onChange != nil {
+			r.onChange()
+		}
+	}
+	return addrs, nil
+}
+
+// wrap returns a dial function that connects to the resolved addresses in turn
+func (r *dnsResolver) wrap(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := r.resolve(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			if !matchesFamily(network, ip) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+		}
+		return nil, lastErr
+	}
+}
+
+// matchesFamily reports whether ip can be dialed on network (tcp, tcp4 or tcp6)
+func matchesFamily(network, ip string) bool {
+	isV4 := net.ParseIP(ip).To4() != nil
+	switch network {
+	case "tcp4":
+		return isV4
+	case "tcp6":
+		return !isV4
+	default:
+		return true
+	}
+}
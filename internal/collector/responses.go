@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// responseCounter counts the responses Kibana sends the exporter by status
+// code, redirects included, so that credentials that start failing or a
+// gateway that sheds load show up as a trend. It sits in the HTTP client, so
+// it has its own lock: the health check and parallel collectors share the
+// client.
+type responseCounter struct {
+	next   http.RoundTripper
+	mutex  sync.Mutex
+	counts map[int]uint64
+	desc   *prometheus.Desc
+}
+
+func newResponseCounter(next http.RoundTripper) *responseCounter {
+	return &responseCounter{
+		next:   next,
+		counts: make(map[int]uint64),
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "upstream_responses_total"),
+			"Responses Kibana sent to the exporter's requests, by HTTP status code",
+			[]string{"code"}, nil,
+		),
+	}
+}
+
+func (r *responseCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err == nil {
+		r.mutex.Lock()
+		r.counts[resp.StatusCode]++
+		r.mutex.Unlock()
+	}
+	return resp, err
+}
+
+func (r *responseCounter) describe(ch chan<- *prometheus.Desc) {
+	ch <- r.desc
+}
+
+func (r *responseCounter) export(ch chan<- prometheus.Metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, code := range slices.Sorted(maps.Keys(r.counts)) {
+		ch <- prometheus.MustNewConstMetric(r.desc, prometheus.CounterValue, float64(r.counts[code]), strconv.Itoa(code))
+	}
+}
@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Response time statistics accepted by ResponseTimeConfig.Stats
+const (
+	ResponseTimeAvg = "avg"
+	ResponseTimeMax = "max"
+	ResponseTimeP95 = "p95"
+	ResponseTimeP99 = "p99"
+)
+
+// Metric structures accepted by ResponseTimeConfig.Structure
+const (
+	// ResponseTimeLabels exports kibana_response_time_seconds{quantile="avg"}
+	ResponseTimeLabels = "labels"
+	// ResponseTimeSeparate exports kibana_response_time_avg_seconds
+	ResponseTimeSeparate = "separate"
+	// ResponseTimeBoth exports both structures, e.g. while migrating dashboards
+	ResponseTimeBoth = "both"
+)
+
+// DefaultResponseTimeStats are exported when ResponseTimeConfig.Stats is empty
+var DefaultResponseTimeStats = []string{ResponseTimeAvg, ResponseTimeMax}
+
+// ResponseTimeConfig selects the response time statistics and how they are
+// exported. The zero value exports avg and max as quantile labels.
+type ResponseTimeConfig struct {
+	Stats     []string
+	Structure string
+}
+
+// Validate checks the statistics and structure names
+func (r ResponseTimeConfig) Validate() error {
+	for i, stat := range r.Stats {
+		switch stat {
+		case ResponseTimeAvg, ResponseTimeMax, ResponseTimeP95, ResponseTimeP99:
+		default:
+			return fmt.Errorf("unknown response time statistic %q, expected avg, max, p95 or p99", stat)
+		}
+		if slices.Contains(r.Stats[:i], stat) {
+			return fmt.Errorf("duplicate response time statistic %q", stat)
+		}
+	}
+	switch r.Structure {
+	case "", ResponseTimeLabels, ResponseTimeSeparate, ResponseTimeBoth:
+	default:
+		return fmt.Errorf("unknown response time structure %q, expected labels, separate or both", r.Structure)
+	}
+	return nil
+}
+
+// responseTimeDescs exports the configured response time statistics as a
+// quantile label, as one metric per statistic, or both
+type responseTimeDescs struct {
+	stats []string
+	// labeled is set for ResponseTimeLabels and ResponseTimeBoth
+	labeled *prometheus.Desc
+	// separate is set for ResponseTimeSeparate and ResponseTimeBoth
+	separate map[string]*prometheus.Desc
+}
+
+func newResponseTimeDescs(config ResponseTimeConfig) responseTimeDescs {
+	d := responseTimeDescs{stats: config.Stats}
+	if len(d.stats) == 0 {
+		d.stats = DefaultResponseTimeStats
+	}
+	if config.Structure != ResponseTimeSeparate {
+		d.labeled = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "response_time", "seconds"),
+			"Response time statistics",
+			[]string{"quantile"}, nil,
+		)
+	}
+	if config.Structure == ResponseTimeSeparate || config.Structure == ResponseTimeBoth {
+		d.separate = make(map[string]*prometheus.Desc)
+		for _, stat := range d.stats {
+			d.separate[stat] = prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "response_time", stat+"_seconds"),
+				"Response time statistic "+stat,
+				nil, nil,
+			)
+		}
+	}
+	return d
+}
+
+func (d responseTimeDescs) describe(ch chan<- *prometheus.Desc) {
+	if d.labeled != nil {
+		ch <- d.labeled
+	}
+	for _, stat := range d.stats {
+		if desc, ok := d.separate[stat]; ok {
+			ch <- desc
+		}
+	}
+}
+
+// export exports the statistics the status reports; p95 and p99 are only
+// reported by some Kibana builds
+func (d responseTimeDescs) export(ch chan<- prometheus.Metric, rt *ResponseTimeMetrics) {
+	values := map[string]*float64{
+		ResponseTimeAvg: rt.Avg,
+		ResponseTimeMax: rt.Max,
+		ResponseTimeP95: rt.P95,
+		ResponseTimeP99: rt.P99,
+	}
+	for _, stat := range d.stats {
+		ms := values[stat]
+		if ms == nil {
+			continue
+		}
+		if d.labeled != nil {
+			ch <- prometheus.MustNewConstMetric(d.labeled, prometheus.GaugeValue, *ms/1000.0, stat)
+		}
+		if desc, ok := d.separate[stat]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, *ms/1000.0)
+		}
+	}
+}
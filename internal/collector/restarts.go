@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// restartDetector counts Kibana restarts, detected as a decrease of the
+// reported uptime between scrapes. Guarded by the collector mutex.
+type restartDetector struct {
+	uptime      time.Duration
+	restarts    uint64
+	lastRestart time.Time
+
+	restartsDesc    *prometheus.Desc
+	lastRestartDesc *prometheus.Desc
+}
+
+func newRestartDetector() restartDetector {
+	return restartDetector{
+		restartsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "restarts_total"),
+			"Kibana restarts detected from a decrease of the reported uptime",
+			nil, nil,
+		),
+		lastRestartDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_restart_timestamp_seconds"),
+			"Unix timestamp at which Kibana last restarted, derived from its uptime (0 if none was detected)",
+			nil, nil,
+		),
+	}
+}
+
+func (d *restartDetector) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.restartsDesc
+	ch <- d.lastRestartDesc
+}
+
+// observe records the uptime reported by a successful scrape
+func (d *restartDetector) observe(url string, uptime time.Duration) {
+	if d.uptime > 0 && uptime < d.uptime {
+		d.restarts++
+		d.lastRestart = time.Now().Add(-uptime)
+		log.WithFields(log.Fields{"kibana_url": url, "uptime": uptime.Round(time.Second).String()}).Warn("Kibana restarted")
+	}
+	d.uptime = uptime
+}
+
+func (d *restartDetector) export(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(d.restartsDesc, prometheus.CounterValue, float64(d.restarts))
+	ch <- prometheus.MustNewConstMetric(d.lastRestartDesc, prometheus.GaugeValue, unixSeconds(d.lastRestart))
+}
@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// API schemas of /api/status
+const (
+	// SchemaAuto accepts both formats, preferring the Kibana 8 fields when present
+	SchemaAuto = "auto"
+	// SchemaKibana8 is the level-based format of Kibana 8 and later
+	SchemaKibana8 = "kibana8"
+	// SchemaKibana7 is the legacy green/yellow/red format of Kibana 7
+	SchemaKibana7 = "kibana7"
+	// SchemaOpenSearch is the legacy format of OpenSearch Dashboards
+	SchemaOpenSearch = "opensearch"
+)
+
+// ValidSchema reports whether schema is a known schema ("" means auto)
+func ValidSchema(schema string) bool {
+	switch schema {
+	case "", SchemaAuto, SchemaKibana8, SchemaKibana7, SchemaOpenSearch:
+		return true
+	}
+	return false
+}
+
+// detectSchema returns the schema of a decoded, not yet normalized status: the
+// pinned schema, or for auto the one its fields and version point to
+func detectSchema(status *KibanaStatus, pinned string) string {
+	if pinned != "" && pinned != SchemaAuto {
+		return pinned
+	}
+	switch {
+	case status.Status.Overall.Level != "":
+		return SchemaKibana8
+	case status.Status.Overall.State == "":
+		return ""
+	case strings.HasPrefix(status.Version.Number, "7."):
+		return SchemaKibana7
+	default:
+		// OpenSearch Dashboards versions start at 1.0
+		return SchemaOpenSearch
+	}
+}
+
+// legacyLevels maps legacy states to Kibana 8 levels
+var legacyLevels = map[string]string{
+	"green":  "available",
+	"yellow": "degraded",
+	"red":    "unavailable",
+}
+
+// normalizeStatus converts a decoded status to the Kibana 8 shape that the
+// rest of the collector works with, according to the pinned schema
+func normalizeStatus(status *KibanaStatus, schema string) error {
+	switch schema {
+	case SchemaKibana8:
+		if status.Status.Overall.Level == "" {
+			return newScrapeError(ErrSchema, "status.overall.level missing, the target does not look like Kibana 8")
+		}
+		return nil
+	case SchemaKibana7, SchemaOpenSearch:
+		if status.Status.Overall.State == "" {
+			return newScrapeError(ErrSchema, "status.overall.state missing, the target does not look like %s", schema)
+		}
+	default:
+		if status.Status.Overall.Level != "" || status.Status.Overall.State == "" {
+			return nil
+		}
+	}
+
+	status.Status.Overall.Level = legacyLevel(status.Status.Overall.State)
+	if status.Status.Core == nil {
+		status.Status.Core = make(map[string]*ServiceStatus)
+	}
+	if status.Status.Plugins == nil {
+		status.Status.Plugins = make(map[string]*ServiceStatus)
+	}
+	for _, s := range status.Status.Statuses {
+		kind, name, ok := parseLegacyID(s.ID)
+		if !ok {
+			continue
+		}
+		// OpenSearch Dashboards reports its backend as opensearch
+		if name == "opensearch" {
+			name = "elasticsearch"
+		}
+		svc := &ServiceStatus{Level: legacyLevel(s.State), Summary: s.Message}
+		if kind == "core" {
+			status.Status.Core[name] = svc
+		} else {
+			status.Status.Plugins[name] = svc
+		}
+	}
+	return nil
+}
+
+func legacyLevel(state string) string {
+	if level, ok := legacyLevels[state]; ok {
+		return level
+	}
+	return state
+}
+
+// parseLegacyID splits a legacy status id such as core:elasticsearch@7.17.0
+func parseLegacyID(id string) (kind, name string, ok bool) {
+	kind, rest, ok := strings.Cut(id, ":")
+	if !ok {
+		return "", "", false
+	}
+	name, _, _ = strings.Cut(rest, "@")
+	return kind, name, name != ""
+}
+
+// errUnknownSchema is returned for schemas not accepted by ValidSchema
+func errUnknownSchema(schema string) error {
+	return fmt.Errorf("unknown schema %q, expected auto, kibana8, kibana7 or opensearch", schema)
+}
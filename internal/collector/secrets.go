@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/pkg/kibana"
+)
+
+// ReadSecretFile returns the content of a credentials file without
+// surrounding whitespace, such as the trailing newline of a Docker secret
+func ReadSecretFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", file)
+	}
+	return value, nil
+}
+
+// secretFiles reads the credential files of Config.UsernameFile,
+// PasswordFile and AuthParamFiles, so rotated Kubernetes or Docker secrets
+// apply without a restart. Guarded by KibanaCollector.authMutex.
+type secretFiles struct {
+	usernameFile string
+	passwordFile string
+	paramFiles   map[string]string
+	// values of the last read, by file
+	values map[string]string
+}
+
+func newSecretFiles(config Config) *secretFiles {
+	if config.UsernameFile == "" && config.PasswordFile == "" && len(config.AuthParamFiles) == 0 {
+		return nil
+	}
+	return &secretFiles{
+		usernameFile: config.UsernameFile,
+		passwordFile: config.PasswordFile,
+		paramFiles:   config.AuthParamFiles,
+	}
+}
+
+// read reads every file and reports whether a value changed since the last read
+func (s *secretFiles) read() (bool, error) {
+	values := make(map[string]string, len(s.paramFiles)+2)
+	for _, file := range append([]string{s.usernameFile, s.passwordFile}, slices.Collect(maps.Values(s.paramFiles))...) {
+		if file == "" {
+			continue
+		}
+		value, err := ReadSecretFile(file)
+		if err != nil {
+			return false, err
+		}
+		values[file] = value
+	}
+	changed := !maps.Equal(values, s.values)
+	s.values = values
+	return changed, nil
+}
+
+// apply returns credentials with those of the last read replacing them
+func (s *secretFiles) apply(credentials kibana.AuthConfig) kibana.AuthConfig {
+	if s.usernameFile != "" {
+		credentials.Username = s.values[s.usernameFile]
+	}
+	if s.passwordFile != "" {
+		credentials.Password = s.values[s.passwordFile]
+	}
+	if len(s.paramFiles) > 0 {
+		credentials.Params = maps.Clone(credentials.Params)
+		if credentials.Params == nil {
+			credentials.Params = make(map[string]string)
+		}
+		for name, file := range s.paramFiles {
+			credentials.Params[name] = s.values[file]
+		}
+	}
+	return credentials
+}
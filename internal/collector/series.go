@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesCounts records how many series each collector sent during the last
+// scrape, to attribute cardinality. Guarded by the collector mutex.
+type seriesCounts struct {
+	counts map[string]int
+	desc   *prometheus.Desc
+}
+
+func newSeriesCounts() seriesCounts {
+	return seriesCounts{
+		counts: map[string]int{},
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "series_count"),
+			"Series sent by each collector during the last scrape",
+			[]string{"collector"}, nil,
+		),
+	}
+}
+
+// reset forgets the previous scrape, so collectors that no longer run drop out
+func (s *seriesCounts) reset() {
+	clear(s.counts)
+}
+
+// count runs collect with a channel that forwards to ch and records the
+// number of series sent under name
+func (s *seriesCounts) count(ch chan<- prometheus.Metric, name string, collect func(chan<- prometheus.Metric) bool) bool {
+	forward := make(chan prometheus.Metric)
+	sent := make(chan int)
+	go func() {
+		n := 0
+		for m := range forward {
+			ch <- m
+			n++
+		}
+		sent <- n
+	}()
+	ok := collect(forward)
+	close(forward)
+	s.counts[name] = <-sent
+	return ok
+}
+
+// split moves n of the series counted for one collector to another that
+// runs as part of it
+func (s *seriesCounts) split(from, to string, n int) {
+	s.counts[from] -= n
+	s.counts[to] = n
+}
+
+func (s *seriesCounts) export(ch chan<- prometheus.Metric) {
+	for _, name := range slices.Sorted(maps.Keys(s.counts)) {
+		ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, float64(s.counts[name]), name)
+	}
+}
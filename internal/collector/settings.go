@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CollectorSettings configure one collector from the collectors block of a
+// targets file
+type CollectorSettings struct {
+	// Enabled turns the collector on or off, overriding its flag (nil keeps the flag)
+	Enabled *bool
+	// Timeout bounds the time the collector may spend per scrape, on top of
+	// the per-request timeout (0 for no extra limit)
+	Timeout time.Duration
+	// Workers bounds how many targets run the collector at once (0 for no
+	// limit), and Queue how many more may wait for a worker (0 for no limit)
+	Workers int
+	Queue   int
+}
+
+type collectorSettingsJSON struct {
+	Enabled *bool  `json:"enabled"`
+	Timeout string `json:"timeout"`
+	Workers int    `json:"workers"`
+	Queue   int    `json:"queue"`
+}
+
+// LoadCollectorSettings reads the collectors block of a targets file, keyed by
+// collector name. Unknown collectors and settings are rejected.
+func LoadCollectorSettings(file string) (map[string]CollectorSettings, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Collectors map[string]json.RawMessage `json:"collectors"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+
+	settings := make(map[string]CollectorSettings, len(config.Collectors))
+	for name, raw := range config.Collectors {
+		if !ValidCollector(name) {
+			return nil, fmt.Errorf("%s: unknown collector %q, expected status, auth, spaces, custom, ui_settings, cluster_info, deprecations, saved_objects_probe, frontend or opensearch_plugins", file, name)
+		}
+		var s collectorSettingsJSON
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%s: collector %q: %w", file, name, err)
+		}
+		if name == CollectorStatus && s.Enabled != nil && !*s.Enabled {
+			return nil, fmt.Errorf("%s: the status collector cannot be disabled", file)
+		}
+
+		var timeout time.Duration
+		if s.Timeout != "" {
+			if timeout, err = time.ParseDuration(s.Timeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("%s: collector %q: invalid timeout %q", file, name, s.Timeout)
+			}
+		}
+		if s.Workers < 0 || s.Queue < 0 {
+			return nil, fmt.Errorf("%s: collector %q: workers and queue must not be negative", file, name)
+		}
+		if s.Queue > 0 && s.Workers == 0 {
+			return nil, fmt.Errorf("%s: collector %q: queue needs workers", file, name)
+		}
+		settings[name] = CollectorSettings{Enabled: s.Enabled, Timeout: timeout, Workers: s.Workers, Queue: s.Queue}
+	}
+	return settings, nil
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// payloadShape tracks the structure of the /api/status payload: its
+// top-level keys and the keys of its top-level objects, e.g. metrics.os.
+// Kibana adding or removing fields shows up as a new shape hash before it
+// breaks anything. Guarded by the collector mutex.
+type payloadShape struct {
+	keys    []string
+	hash    string
+	changes uint64
+
+	infoDesc    *prometheus.Desc
+	changesDesc *prometheus.Desc
+}
+
+func newPayloadShape() payloadShape {
+	return payloadShape{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "payload_shape_info"),
+			"Hash of the key structure of the status payload; always 1",
+			[]string{"hash"}, nil,
+		),
+		changesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "payload_changes_total"),
+			"Changes of the key structure of the status payload between scrapes",
+			nil, nil,
+		),
+	}
+}
+
+func (s *payloadShape) describe(ch chan<- *prometheus.Desc) {
+	ch <- s.infoDesc
+	ch <- s.changesDesc
+}
+
+// observe records the shape of a status payload that decoded successfully
+// and logs the keys that were added or removed since the previous one
+func (s *payloadShape) observe(url string, payload []byte) {
+	keys := shapeKeys(payload)
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(keys, "\n")))
+	hash := fmt.Sprintf("%08x", h.Sum32())
+	if s.hash != "" && hash != s.hash {
+		s.changes++
+		log.WithFields(log.Fields{
+			"kibana_url": url,
+			"added":      strings.Join(missingKeys(keys, s.keys), ","),
+			"removed":    strings.Join(missingKeys(s.keys, keys), ","),
+		}).Info("Status payload structure changed")
+	}
+	s.keys, s.hash = keys, hash
+}
+
+// shapeKeys returns the sorted top-level keys of a JSON object and, for
+// values that are objects, their keys as parent.child
+func shapeKeys(payload []byte) []string {
+	var top map[string]json.RawMessage
+	if json.Unmarshal(payload, &top) != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(top))
+	for key, value := range top {
+		keys = append(keys, key)
+		if !bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+			continue
+		}
+		var children map[string]json.RawMessage
+		if json.Unmarshal(value, &children) == nil {
+			for child := range children {
+				keys = append(keys, key+"."+child)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// missingKeys returns the keys of a that are not in the sorted b
+func missingKeys(a, b []string) []string {
+	var missing []string
+	for _, key := range a {
+		if _, found := slices.BinarySearch(b, key); !found {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+func (s *payloadShape) export(ch chan<- prometheus.Metric) {
+	if s.hash == "" {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(s.infoDesc, prometheus.GaugeValue, 1, s.hash)
+	ch <- prometheus.MustNewConstMetric(s.changesDesc, prometheus.CounterValue, float64(s.changes))
+}
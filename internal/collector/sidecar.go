@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kibana startup phases reported by kibana_startup_phase
+const (
+	PhaseUnreachable = "unreachable"
+	PhaseMigrating   = "migrating"
+	PhaseStarting    = "starting"
+	PhaseReady       = "ready"
+)
+
+var startupPhases = []string{PhaseUnreachable, PhaseMigrating, PhaseStarting, PhaseReady}
+
+const (
+	minProbeBackoff = 1 * time.Second
+	maxProbeBackoff = 30 * time.Second
+)
+
+// startupPhase derives Kibana's boot phase from a status scrape. While saved object
+// migrations run, Kibana answers every request with 503 "Kibana server is not ready yet".
+func startupPhase(status *KibanaStatus, err error) string {
+	if err == nil {
+		if status != nil && (status.Status.Overall.Level == "available" || status.Status.Overall.Level == "green") {
+			return PhaseReady
+		}
+		return PhaseStarting
+	}
+
+	var scrapeErr *ScrapeError
+	if errors.As(err, &scrapeErr) && scrapeErr.StatusCode == http.StatusServiceUnavailable {
+		if strings.Contains(strings.ToLower(scrapeErr.Body), "not ready yet") {
+			return PhaseMigrating
+		}
+		return PhaseStarting
+	}
+	if errors.Is(err, ErrConnection) || errors.Is(err, ErrTimeout) {
+		return PhaseUnreachable
+	}
+	return PhaseStarting
+}
+
+// healthProbe caches readiness results while Kibana is booting so that
+// readiness probes do not hammer a Kibana that is still running migrations
+type healthProbe struct {
+	mutex   sync.Mutex
+	err     error
+	next    time.Time
+	backoff time.Duration
+}
+
+// check returns the cached result if the backoff has not elapsed, otherwise runs probe
+func (h *healthProbe) check(probe func() error) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if time.Now().Before(h.next) {
+		return h.err
+	}
+
+	h.err = probe()
+	switch startupPhase(nil, h.err) {
+	case PhaseUnreachable, PhaseMigrating:
+		h.backoff *= 2
+		if h.backoff < minProbeBackoff {
+			h.backoff = minProbeBackoff
+		} else if h.backoff > maxProbeBackoff {
+			h.backoff = maxProbeBackoff
+		}
+		h.next = time.Now().Add(h.backoff)
+	default:
+		h.backoff = 0
+		h.next = time.Time{}
+	}
+	return h.err
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// scrapeTimings records how long each step of one scrape took
+type scrapeTimings struct {
+	last   time.Time
+	fields log.Fields
+}
+
+func newScrapeTimings(start time.Time) *scrapeTimings {
+	return &scrapeTimings{last: start, fields: make(log.Fields)}
+}
+
+// lap records the time since the previous lap under name
+func (t *scrapeTimings) lap(name string) {
+	now := time.Now()
+	t.fields[name+"_seconds"] = now.Sub(t.last).Seconds()
+	t.last = now
+}
+
+// slowScrapes counts scrapes exceeding Config.SlowScrapeThreshold and logs
+// where their time went, as an early warning before scrapes time out
+type slowScrapes struct {
+	threshold time.Duration
+	total     atomic.Uint64
+	desc      *prometheus.Desc
+}
+
+func newSlowScrapes(threshold time.Duration) *slowScrapes {
+	return &slowScrapes{
+		threshold: threshold,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "slow_scrapes_total"),
+			"Number of scrapes that took longer than --slow-scrape-threshold",
+			nil, nil,
+		),
+	}
+}
+
+// observe checks the scrape that started at start and exports the counter.
+// phases are logged when the status request ran.
+func (s *slowScrapes) observe(ch chan<- prometheus.Metric, url string, start time.Time, timings *scrapeTimings, phases *scrapePhases) {
+	if duration := time.Since(start); duration > s.threshold {
+		s.total.Add(1)
+		fields := log.Fields{
+			"kibana_url":       url,
+			"duration_seconds": duration.Seconds(),
+			"threshold":        s.threshold.String(),
+		}
+		for name, value := range timings.fields {
+			fields[name] = value
+		}
+		if phases != nil {
+			phases.each(func(name string, d time.Duration) {
+				fields["status_"+name+"_seconds"] = d.Seconds()
+			})
+		}
+		log.WithFields(fields).Warn("Slow scrape")
+	}
+	ch <- prometheus.MustNewConstMetric(s.desc, prometheus.CounterValue, float64(s.total.Load()))
+}
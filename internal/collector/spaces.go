@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collectors that can run per space
+const (
+	SpaceSavedObjects  = "saved_objects"
+	SpaceAlertingRules = "alerting_rules"
+	SpaceDataViews     = "data_views"
+)
+
+// SpacesConfig enables per-space enumeration. Every space is listed on each
+// scrape and the selected collectors run in it, labeling series with space.
+type SpacesConfig struct {
+	Enabled    bool
+	Collectors []string
+	// SavedObjectTypes are counted by the saved_objects collector
+	SavedObjectTypes []string
+}
+
+// DefaultSavedObjectTypes are the saved object types counted per space by default
+var DefaultSavedObjectTypes = []string{"dashboard", "visualization", "lens", "search", "index-pattern", "map"}
+
+type space struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type findResponse struct {
+	Total int `json:"total"`
+}
+
+type spacesDescs struct {
+	info         *prometheus.Desc
+	savedObjects *prometheus.Desc
+	rules        *prometheus.Desc
+	dataViews    *prometheus.Desc
+}
+
+func newSpacesDescs() spacesDescs {
+	return spacesDescs{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "space", "info"),
+			"Kibana space, always 1",
+			[]string{"space", "name"}, nil,
+		),
+		savedObjects: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "space", "saved_objects"),
+			"Number of saved objects in a space by type",
+			[]string{"space", "type"}, nil,
+		),
+		rules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "space", "alerting_rules"),
+			"Number of alerting rules in a space",
+			[]string{"space"}, nil,
+		),
+		dataViews: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "space", "data_views"),
+			"Number of data views in a space",
+			[]string{"space"}, nil,
+		),
+	}
+}
+
+func (d spacesDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.info
+	ch <- d.savedObjects
+	ch <- d.rules
+	ch <- d.dataViews
+}
+
+// spacePath prefixes an API path with the space, leaving the default space unprefixed
+func spacePath(spaceID, path string) string {
+	if spaceID == "default" {
+		return path
+	}
+	return "/s/" + url.PathEscape(spaceID) + path
+}
+
+// collectSpaces lists all spaces and runs the enabled per-space collectors
+func (c *KibanaCollector) collectSpaces(ctx context.Context, ch chan<- prometheus.Metric) {
+	const spacesEndpoint = "/api/spaces/space"
+	if c.forbidden.skip(CollectorSpaces, spacesEndpoint) {
+		return
+	}
+	var spaces []space
+	if err := c.getJSON(ctx, spacesEndpoint, &spaces); err != nil {
+		c.logSpaceError(err, "", CollectorSpaces, spacesEndpoint)
+		return
+	}
+
+	for _, s := range spaces {
+		ch <- prometheus.MustNewConstMetric(c.spaces.info, prometheus.GaugeValue, 1, s.ID, s.Name)
+		for _, name := range c.config.Spaces.Collectors {
+			switch name {
+			case SpaceSavedObjects:
+				c.collectSpaceSavedObjects(ctx, ch, s.ID)
+			case SpaceAlertingRules:
+				const endpoint = "/api/alerting/rules/_find"
+				if c.forbidden.skip(name, endpoint) {
+					continue
+				}
+				var rules findResponse
+				if err := c.getJSON(ctx, spacePath(s.ID, endpoint+"?per_page=0"), &rules); err != nil {
+					c.logSpaceError(err, s.ID, name, endpoint)
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(c.spaces.rules, prometheus.GaugeValue, float64(rules.Total), s.ID)
+			case SpaceDataViews:
+				const endpoint = "/api/data_views"
+				if c.forbidden.skip(name, endpoint) {
+					continue
+				}
+				var views struct {
+					DataViews []struct{} `json:"data_view"`
+				}
+				if err := c.getJSON(ctx, spacePath(s.ID, endpoint), &views); err != nil {
+					c.logSpaceError(err, s.ID, name, endpoint)
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(c.spaces.dataViews, prometheus.GaugeValue, float64(len(views.DataViews)), s.ID)
+			}
+		}
+	}
+}
+
+func (c *KibanaCollector) collectSpaceSavedObjects(ctx context.Context, ch chan<- prometheus.Metric, spaceID string) {
+	types := c.config.Spaces.SavedObjectTypes
+	if len(types) == 0 {
+		types = DefaultSavedObjectTypes
+	}
+	const endpoint = "/api/saved_objects/_find"
+	for _, t := range types {
+		if c.forbidden.skip(SpaceSavedObjects, endpoint) {
+			return
+		}
+		var found findResponse
+		path := spacePath(spaceID, endpoint+"?per_page=0&type="+url.QueryEscape(t))
+		if err := c.getJSON(ctx, path, &found); err != nil {
+			c.logSpaceError(err, spaceID, SpaceSavedObjects, endpoint)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.spaces.savedObjects, prometheus.GaugeValue, float64(found.Total), spaceID, t)
+	}
+}
+
+// logSpaceError logs a failed space request, unless it was a 403 that put
+// the endpoint into its cooldown
+func (c *KibanaCollector) logSpaceError(err error, spaceID, collector, endpoint string) {
+	if c.forbidden.observe(c.config.KibanaURL, collector, endpoint, err) {
+		return
+	}
+	log.WithError(err).WithFields(log.Fields{
+		"target":     c.config.KibanaURL,
+		"space":      spaceID,
+		"collector":  collector,
+		"error_code": ErrorCode(err),
+	}).Warn("Failed to collect space metrics")
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// ScrapeState summarizes the outcome of the most recent scrapes of a target
+type ScrapeState struct {
+	URL             string    `json:"url"`
+	LastScrape      time.Time `json:"last_scrape,omitzero"`
+	LastScrapeAge   float64   `json:"last_scrape_age_seconds"`
+	LastDuration    float64   `json:"last_scrape_duration_seconds"`
+	LastSuccess     bool      `json:"last_scrape_success"`
+	LastSuccessTime time.Time `json:"last_success,omitzero"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorCode   string    `json:"last_error_code,omitempty"`
+	LastErrorTime   time.Time `json:"last_error_time,omitzero"`
+	TotalScrapes    uint64    `json:"total_scrapes"`
+	FailedScrapes   uint64    `json:"failed_scrapes"`
+	// Schema is the /api/status schema of the last successful scrape
+	Schema string `json:"schema,omitempty"`
+	// Version, Name and UUID are reported by Kibana in the last successful scrape
+	Version string `json:"version,omitempty"`
+	Name    string `json:"name,omitempty"`
+	UUID    string `json:"uuid,omitempty"`
+}
+
+// scrapeTracker records scrape outcomes; it is safe for concurrent use
+type scrapeTracker struct {
+	mutex sync.RWMutex
+	state ScrapeState
+}
+
+func (t *scrapeTracker) record(start time.Time, duration time.Duration, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.state.LastScrape = start
+	t.state.LastDuration = duration.Seconds()
+	t.state.TotalScrapes++
+	if err != nil {
+		t.state.LastSuccess = false
+		t.state.LastError = err.Error()
+		t.state.LastErrorCode = ErrorCode(err)
+		t.state.LastErrorTime = start
+		t.state.FailedScrapes++
+		return
+	}
+	t.state.LastSuccess = true
+	t.state.LastSuccessTime = start
+}
+
+// setPayload records what Kibana reported about itself in a successful scrape
+func (t *scrapeTracker) setPayload(schema string, status *KibanaStatus) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.state.Schema = schema
+	t.state.Version = status.Version.Number
+	t.state.Name = status.Name
+	t.state.UUID = status.UUID
+}
+
+func (t *scrapeTracker) snapshot() ScrapeState {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	state := t.state
+	if !state.LastScrape.IsZero() {
+		state.LastScrapeAge = time.Since(state.LastScrape).Seconds()
+	}
+	return state
+}
+
+func (t *scrapeTracker) lastErrorTime() time.Time {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.state.LastErrorTime
+}
@@ -0,0 +1,34 @@
+package collector
+
+import "time"
+
+// StateDown is the state of a target whose last scrape failed
+const StateDown = "down"
+
+// StateChange reports that a target went up or down or changed its overall
+// status level between two scrapes
+type StateChange struct {
+	URL  string
+	From string
+	To   string
+	Time time.Time
+	// Error is the scrape error when To is StateDown
+	Error string
+}
+
+// observeState calls Config.OnStateChange when the state differs from that
+// of the previous scrape. Guarded by the collector mutex.
+func (c *KibanaCollector) observeState(status *KibanaStatus, err error) {
+	state, errText := StateDown, ""
+	if err != nil {
+		errText = err.Error()
+	} else {
+		state = status.Status.Overall.Level
+	}
+	previous := c.state
+	c.state = state
+	if c.config.OnStateChange == nil || previous == "" || previous == state {
+		return
+	}
+	c.config.OnStateChange(StateChange{URL: c.config.KibanaURL, From: previous, To: state, Time: time.Now(), Error: errText})
+}
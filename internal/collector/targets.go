@@ -0,0 +1,472 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collectors of a target. Status always runs for targets; the others are
+// optional and can be selected per target or per request.
+const (
+	CollectorStatus = "status"
+	CollectorAuth   = "auth"
+	CollectorSpaces = "spaces"
+	CollectorCustom = "custom"
+	// CollectorUISettings exports advanced settings to detect config drift
+	CollectorUISettings = "ui_settings"
+	// CollectorClusterInfo links Kibana to its Elasticsearch cluster
+	CollectorClusterInfo = "cluster_info"
+	// CollectorDeprecations tracks upgrade readiness
+	CollectorDeprecations = "deprecations"
+	// CollectorSavedObjectsProbe checks the Kibana to Elasticsearch data path
+	CollectorSavedObjectsProbe = "saved_objects_probe"
+	// CollectorFrontend checks that the Kibana UI is served
+	CollectorFrontend = "frontend"
+	// CollectorOpenSearchPlugins reads OpenSearch Dashboards plugin APIs
+	CollectorOpenSearchPlugins = "opensearch_plugins"
+)
+
+// ValidCollector reports whether name is a known collector
+func ValidCollector(name string) bool {
+	switch name {
+	case CollectorStatus, CollectorAuth, CollectorSpaces, CollectorCustom, CollectorUISettings, CollectorClusterInfo, CollectorDeprecations,
+		CollectorSavedObjectsProbe, CollectorFrontend, CollectorOpenSearchPlugins:
+		return true
+	}
+	return false
+}
+
+// selection is a set of collector names; nil selects every collector
+type selection map[string]bool
+
+func (s selection) has(name string) bool {
+	return s == nil || s[name]
+}
+
+// collectorView runs a subset of a KibanaCollector's collectors
+type collectorView struct {
+	collector *KibanaCollector
+	selected  selection
+}
+
+func (v collectorView) Describe(ch chan<- *prometheus.Desc) { v.collector.Describe(ch) }
+
+func (v collectorView) Collect(ch chan<- prometheus.Metric) { v.collector.collect(ch, v.selected) }
+
+// RegisterViews registers, for every target, a collector running only the
+// named collectors, e.g. to serve a ?collect[] request from a fresh registry
+func (t *Targets) RegisterViews(registerer prometheus.Registerer, collectors []string) error {
+	selected := make(selection, len(collectors))
+	for _, name := range collectors {
+		if !ValidCollector(name) {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+		selected[name] = true
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for url, managed := range t.targets {
+		if err := targetRegisterer(managed.target, registerer).Register(collectorView{collector: managed.collector, selected: selected}); err != nil {
+			return fmt.Errorf("registering %s: %w", url, err)
+		}
+	}
+	return nil
+}
+
+// RegisterTarget registers the collector of the target whose URL, ignoring
+// credentials, is target, e.g. to serve a /probe request from a fresh
+// registry. It reports false if there is no such target.
+func (t *Targets) RegisterTarget(registerer prometheus.Registerer, target string) (bool, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, managed := range t.targets {
+		if withoutCredentials(managed.target.URL) == withoutCredentials(target) {
+			return true, targetRegisterer(managed.target, registerer).Register(collectorView{collector: managed.collector})
+		}
+	}
+	return false, nil
+}
+
+func withoutCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Target is a Kibana instance to scrape, with labels added to all of its metrics
+type Target struct {
+	URL    string            `json:"url"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Schema overrides the configured /api/status schema for this target
+	Schema string `json:"schema,omitempty"`
+	// Collectors restricts the optional collectors (spaces, custom, ui_settings,
+	// cluster_info, deprecations, saved_objects_probe, frontend,
+	// opensearch_plugins) for this target; nil keeps the configured ones.
+	// Status metrics are always collected.
+	Collectors []string `json:"collectors,omitempty"`
+	// Proxy overrides the configured proxy for this target: a URL, env, or
+	// none to connect directly. Empty keeps the configured proxy.
+	Proxy string `json:"proxy,omitempty"`
+	// Username and Password override the shared credentials for this target
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Downtime are recurring windows in which the target is not scraped
+	Downtime []DowntimeWindow `json:"downtime,omitempty"`
+	// MetricPrefix is prepended to the names of the target's metrics, e.g.
+	// prod_ for prod_kibana_up. In a targets file it may be a template.
+	MetricPrefix string `json:"metric_prefix,omitempty"`
+
+	// unrendered is set for targets rendered from the templates of a targets file
+	unrendered *unrenderedTarget
+}
+
+// targetsFile is the format of a targets file
+type targetsFile struct {
+	Targets []Target `json:"targets"`
+	// LabelTemplates are added to every target as labels, e.g.
+	// {"instance": "{{.Host}}"}; MetricPrefix is the default prefix template
+	LabelTemplates map[string]string `json:"label_templates"`
+	MetricPrefix   string            `json:"metric_prefix"`
+}
+
+// LoadTargets reads and validates a JSON targets file
+func LoadTargets(file string) ([]Target, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config targetsFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+
+	if err := applyTemplates(config.Targets, config.LabelTemplates, config.MetricPrefix); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := ValidateTargets(config.Targets); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return config.Targets, nil
+}
+
+// ValidateTargets checks the targets as LoadTargets does for a targets file
+func ValidateTargets(targets []Target) error {
+	seen := make(map[string]bool)
+	// Targets with the same metric prefix export the same metrics, so they
+	// need the same label names and different label values to register
+	byPrefix := make(map[string]Target)
+	byLabels := make(map[string]string)
+	for _, target := range targets {
+		if u, err := url.Parse(target.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid Kibana URL %q", target.URL)
+		}
+		if seen[target.URL] {
+			return fmt.Errorf("duplicate target %q", target.URL)
+		}
+		seen[target.URL] = true
+		if other, ok := byPrefix[target.MetricPrefix]; ok && !sameLabelNames(other.Labels, target.Labels) {
+			return fmt.Errorf("targets %q and %q must have the same label names", other.URL, target.URL)
+		}
+		byPrefix[target.MetricPrefix] = target
+		key := target.MetricPrefix + labelsKey(target.Labels)
+		if other, ok := byLabels[key]; ok {
+			return fmt.Errorf("targets %q and %q have the same labels, add a label such as kibana_instance to tell them apart", other, target.URL)
+		}
+		byLabels[key] = target.URL
+		if !ValidSchema(target.Schema) {
+			return fmt.Errorf("target %q: %w", target.URL, errUnknownSchema(target.Schema))
+		}
+		if err := ValidateProxy(target.Proxy); err != nil {
+			return fmt.Errorf("target %q: %w", target.URL, err)
+		}
+		if target.MetricPrefix != "" && !metricPrefixPattern.MatchString(target.MetricPrefix) {
+			return fmt.Errorf("target %q: invalid metric prefix %q", target.URL, target.MetricPrefix)
+		}
+		for _, name := range target.Collectors {
+			if !ValidCollector(name) || name == CollectorStatus || name == CollectorAuth {
+				return fmt.Errorf("target %q: unknown collector %q, expected spaces, custom, ui_settings, cluster_info, deprecations, saved_objects_probe, frontend or opensearch_plugins", target.URL, name)
+			}
+		}
+	}
+	return nil
+}
+
+// config returns the collector configuration of the target
+func (t Target) config(template Config) Config {
+	config := template
+	config.KibanaURL = t.URL
+	if t.Schema != "" {
+		config.Schema = t.Schema
+	}
+	if t.Username != "" {
+		config.Username, config.Password = t.Username, t.Password
+		config.UsernameFile, config.PasswordFile = "", ""
+	}
+	if t.Proxy != "" {
+		config.Transport.Proxy = t.Proxy
+	}
+	config.Downtime = t.Downtime
+	if t.Collectors != nil {
+		config.Spaces.Enabled = config.Spaces.Enabled && slices.Contains(t.Collectors, CollectorSpaces)
+		if !slices.Contains(t.Collectors, CollectorCustom) {
+			config.CustomEndpoints = nil
+		}
+		if !slices.Contains(t.Collectors, CollectorUISettings) {
+			config.UISettings = nil
+		}
+		config.ClusterInfo = config.ClusterInfo && slices.Contains(t.Collectors, CollectorClusterInfo)
+		config.Deprecations = config.Deprecations && slices.Contains(t.Collectors, CollectorDeprecations)
+		if !slices.Contains(t.Collectors, CollectorSavedObjectsProbe) {
+			config.SavedObjectsProbe = ""
+		}
+		if !slices.Contains(t.Collectors, CollectorFrontend) {
+			config.FrontendProbe = ""
+		}
+		if !slices.Contains(t.Collectors, CollectorOpenSearchPlugins) {
+			config.OpenSearchPlugins = nil
+		}
+	}
+	return config
+}
+
+// Targets is a dynamic set of Kibana collectors. Each target gets its own
+// KibanaCollector registered with the target's labels.
+type Targets struct {
+	registerer prometheus.Registerer
+	config     Config
+
+	mutex   sync.RWMutex
+	targets map[string]*managedTarget
+	paused  atomic.Bool
+}
+
+type managedTarget struct {
+	collector  *KibanaCollector
+	registerer prometheus.Registerer
+	target     Target
+}
+
+// NewTargets creates an empty target set. config is the template for every
+// target's collector; its KibanaURL is replaced by the target URL.
+func NewTargets(registerer prometheus.Registerer, config Config) *Targets {
+	t := &Targets{
+		registerer: registerer,
+		targets:    make(map[string]*managedTarget),
+	}
+	config.paused = &t.paused
+	t.config = config
+	return t
+}
+
+// Set replaces the target set, registering collectors for new targets and
+// unregistering those that are gone. Targets that fail to register are left
+// out and reported in the error.
+func (t *Targets) Set(targets []Target) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.set(targets)
+}
+
+// Replace swaps the target with URL old for target, keeping the rest of the set
+func (t *Targets) Replace(old string, target Target) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.targets[old]; !ok {
+		return fmt.Errorf("unknown target %q", old)
+	}
+	if _, ok := t.targets[target.URL]; ok && target.URL != old {
+		return fmt.Errorf("target %q already exists", target.URL)
+	}
+	targets := []Target{target}
+	previous := make([]Target, 0, len(t.targets))
+	for url, managed := range t.targets {
+		if url != old {
+			targets = append(targets, managed.target)
+		}
+		previous = append(previous, managed.target)
+	}
+	if err := ValidateTargets(targets); err != nil {
+		return err
+	}
+	if err := t.set(targets); err != nil {
+		t.set(previous)
+		return err
+	}
+	return nil
+}
+
+// List returns the current targets, ordered by URL
+func (t *Targets) List() []Target {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	list := make([]Target, 0, len(t.targets))
+	for _, managed := range t.targets {
+		list = append(list, managed.target)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return list
+}
+
+// latestSample returns the latest scrape of the target whose label name has value
+func (t *Targets) latestSample(name, value string) (Sample, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, managed := range t.targets {
+		if managed.target.Labels[name] == value {
+			return managed.collector.history.latest()
+		}
+	}
+	return Sample{}, false
+}
+
+// Pause enters maintenance mode: no target contacts Kibana until Resume is called
+func (t *Targets) Pause() {
+	if !t.paused.Swap(true) {
+		log.Info("Scraping paused for maintenance")
+	}
+}
+
+// Resume leaves maintenance mode
+func (t *Targets) Resume() {
+	if t.paused.Swap(false) {
+		log.Info("Scraping resumed")
+	}
+}
+
+// Paused reports whether scraping is paused for maintenance
+func (t *Targets) Paused() bool {
+	return t.paused.Load()
+}
+
+func (t *Targets) set(targets []Target) error {
+	wanted := make(map[string]Target, len(targets))
+	for _, target := range targets {
+		wanted[target.URL] = target
+	}
+
+	for url, existing := range t.targets {
+		if target, ok := wanted[url]; ok && sameTarget(existing.target, target) {
+			continue
+		}
+		existing.registerer.Unregister(existing.collector)
+		existing.collector.closeIdleConnections()
+		delete(t.targets, url)
+		log.WithField("target", url).Info("Removed Kibana target")
+	}
+
+	var errs []error
+	for url, target := range wanted {
+		if _, ok := t.targets[url]; ok {
+			continue
+		}
+		managed := &managedTarget{
+			collector:  NewKibanaCollector(target.config(t.config)),
+			registerer: targetRegisterer(target, t.registerer),
+			target:     target,
+		}
+		if err := managed.registerer.Register(managed.collector); err != nil {
+			managed.collector.closeIdleConnections()
+			errs = append(errs, fmt.Errorf("registering target %q: %w", url, err))
+			continue
+		}
+		t.targets[url] = managed
+		log.WithFields(log.Fields{"target": url, "labels": target.Labels}).Info("Added Kibana target")
+	}
+	return errors.Join(errs...)
+}
+
+// Collectors returns the collectors of all current targets, ordered by URL
+func (t *Targets) Collectors() []*KibanaCollector {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	urls := make([]string, 0, len(t.targets))
+	for url := range t.targets {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	collectors := make([]*KibanaCollector, 0, len(urls))
+	for _, url := range urls {
+		collectors = append(collectors, t.targets[url].collector)
+	}
+	return collectors
+}
+
+// States returns the scrape state of every target
+func (t *Targets) States() []ScrapeState {
+	collectors := t.Collectors()
+	states := make([]ScrapeState, 0, len(collectors))
+	for _, c := range collectors {
+		states = append(states, c.State())
+	}
+	return states
+}
+
+// targetRegisterer adds the labels and metric prefix of target to the
+// metrics registered with registerer
+func targetRegisterer(target Target, registerer prometheus.Registerer) prometheus.Registerer {
+	if len(target.Labels) > 0 {
+		registerer = prometheus.WrapRegistererWith(target.Labels, registerer)
+	}
+	if target.MetricPrefix != "" {
+		registerer = prometheus.WrapRegistererWithPrefix(target.MetricPrefix, registerer)
+	}
+	return registerer
+}
+
+func sameTarget(a, b Target) bool {
+	return a.Schema == b.Schema && a.Proxy == b.Proxy && a.MetricPrefix == b.MetricPrefix && slices.Equal(a.Collectors, b.Collectors) && sameLabels(a.Labels, b.Labels) &&
+		a.Username == b.Username && a.Password == b.Password && slices.EqualFunc(a.Downtime, b.Downtime, sameDowntime)
+}
+
+func sameDowntime(a, b DowntimeWindow) bool {
+	return slices.Equal(a.Weekdays, b.Weekdays) && a.StartTime == b.StartTime && a.EndTime == b.EndTime && a.Location == b.Location
+}
+
+func sameLabelNames(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// labelsKey identifies a label set independent of map order
+func labelsKey(labels map[string]string) string {
+	key, _ := json.Marshal(labels)
+	return string(key)
+}
+
+func sameLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// metricPrefixPattern matches prefixes that keep metric names valid
+var metricPrefixPattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// targetTemplateData is what label and metric prefix templates are executed
+// with, e.g. {{.Host}} or {{.Labels.cluster}}
+type targetTemplateData struct {
+	URL    string
+	Host   string
+	Labels map[string]string
+}
+
+// targetTemplates are the label templates and metric prefix template of a
+// targets file. Targets rendered from them keep a reference, so that they can
+// be rendered again for a new URL and written back unrendered.
+type targetTemplates struct {
+	labels       map[string]*template.Template
+	metricPrefix string
+}
+
+// unrenderedTarget is what a target of a targets file was rendered from
+type unrenderedTarget struct {
+	templates    *targetTemplates
+	labels       map[string]string
+	metricPrefix string
+}
+
+// applyTemplates renders the label templates and metric prefix template of a
+// targets file into each target. Labels set on the target itself win over
+// templates of the same name, and a target's metric_prefix over the file's.
+func applyTemplates(targets []Target, labelTemplates map[string]string, metricPrefix string) error {
+	templates := &targetTemplates{labels: make(map[string]*template.Template, len(labelTemplates)), metricPrefix: metricPrefix}
+	for name, text := range labelTemplates {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return fmt.Errorf("label template %q: %w", name, err)
+		}
+		templates.labels[name] = tmpl
+	}
+	if len(templates.labels) == 0 && metricPrefix == "" {
+		return nil
+	}
+
+	for i := range targets {
+		targets[i].unrendered = &unrenderedTarget{
+			templates:    templates,
+			labels:       maps.Clone(targets[i].Labels),
+			metricPrefix: targets[i].MetricPrefix,
+		}
+		if err := targets[i].render(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unrendered returns the target as written in its targets file, before its
+// labels and metric prefix were rendered from the file's templates
+func (t Target) Unrendered() Target {
+	if t.unrendered != nil {
+		t.Labels, t.MetricPrefix = maps.Clone(t.unrendered.labels), t.unrendered.metricPrefix
+	}
+	return t
+}
+
+// WithURL returns the target moved to url. Labels and the metric prefix
+// rendered from templates are rendered again for the new URL.
+func (t Target) WithURL(url string) (Target, error) {
+	t = t.Unrendered()
+	t.URL = url
+	if t.unrendered == nil {
+		return t, nil
+	}
+	return t, t.render()
+}
+
+// render renders the templates into the target's labels and metric prefix,
+// which must hold the unrendered values
+func (t *Target) render() error {
+	templates := t.unrendered.templates
+	data := targetTemplateData{URL: t.URL, Labels: maps.Clone(t.Labels)}
+	if data.Labels == nil {
+		data.Labels = make(map[string]string)
+	}
+	if u, err := url.Parse(t.URL); err == nil {
+		data.Host = u.Hostname()
+	}
+	for name, tmpl := range templates.labels {
+		if _, ok := t.Labels[name]; ok {
+			continue
+		}
+		value, err := execute(tmpl, data)
+		if err != nil {
+			return fmt.Errorf("target %q: label %q: %w", t.URL, name, err)
+		}
+		if value == "" {
+			continue
+		}
+		if t.Labels == nil {
+			t.Labels = make(map[string]string)
+		}
+		t.Labels[name] = value
+	}
+
+	prefix := t.MetricPrefix
+	if prefix == "" {
+		prefix = templates.metricPrefix
+	}
+	if prefix == "" {
+		return nil
+	}
+	tmpl, err := template.New("metric_prefix").Option("missingkey=error").Parse(prefix)
+	if err != nil {
+		return fmt.Errorf("target %q: metric prefix: %w", t.URL, err)
+	}
+	if t.MetricPrefix, err = execute(tmpl, data); err != nil {
+		return fmt.Errorf("target %q: metric prefix: %w", t.URL, err)
+	}
+	return nil
+}
+
+func execute(tmpl *template.Template, data targetTemplateData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultRetryAfter is used when a 429 response carries no usable Retry-After header
+const defaultRetryAfter = 30 * time.Second
+
+// maxRetryAfter caps Retry-After so a misconfigured proxy cannot silence the exporter for days
+const maxRetryAfter = 15 * time.Minute
+
+// throttle tracks 429 responses and suppresses requests to Kibana until the
+// server-provided Retry-After has elapsed
+type throttle struct {
+	mutex sync.Mutex
+	until time.Time
+	total atomic.Uint64
+}
+
+// check returns an error if requests are currently suppressed
+func (t *throttle) check() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if remaining := time.Until(t.until); remaining > 0 {
+		return newScrapeError(ErrThrottled, "suppressing requests for another %s after HTTP 429", remaining.Round(time.Second))
+	}
+	return nil
+}
+
+// observe records a response and starts a suppression window on 429
+func (t *throttle) observe(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	t.total.Add(1)
+
+	wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	t.mutex.Lock()
+	t.until = time.Now().Add(wait)
+	t.mutex.Unlock()
+
+	log.WithField("retry_after", wait.String()).Warn("Kibana throttled the exporter, pausing requests")
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	wait := defaultRetryAfter
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = date.Sub(now)
+	}
+
+	if wait <= 0 {
+		return time.Second
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
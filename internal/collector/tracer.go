@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"strings"
+)
+
+// Span kinds as defined by OTLP, passed to Tracer.Start
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// Tracer starts the spans of scrapes. It is implemented on top of
+// internal/tracing by the full build, so that builds without tracing do not
+// link the OTLP exporter. A nil Tracer records nothing.
+type Tracer interface {
+	Start(ctx context.Context, name string, kind int) (context.Context, Span)
+}
+
+// Span is a timed operation of a Tracer. Spans dropped by sampling are
+// still returned and record nothing; their TraceParent is empty.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	AddEvent(name string, attributes map[string]interface{})
+	RecordError(err error)
+	TraceParent() string
+	End()
+}
+
+// startRequestSpan starts the client span of a GET of a Kibana path
+func (c *KibanaCollector) startRequestSpan(ctx context.Context, path string) (context.Context, Span) {
+	name, _, _ := strings.Cut(path, "?")
+	ctx, span := c.config.Tracer.Start(ctx, "GET "+name, spanKindClient)
+	span.SetAttribute("http.method", "GET")
+	span.SetAttribute("http.target", path)
+	span.SetAttribute("http.url", c.config.KibanaURL+path)
+	return ctx, span
+}
+
+// noopTracer is used when Config.Tracer is nil
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string, kind int) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{})              {}
+func (noopSpan) AddEvent(name string, attributes map[string]interface{}) {}
+func (noopSpan) RecordError(err error)                                   {}
+func (noopSpan) TraceParent() string                                     { return "" }
+func (noopSpan) End()                                                    {}
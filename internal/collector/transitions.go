@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+type transition struct {
+	from, to string
+}
+
+// statusTransitions counts changes of the overall status level between
+// successful scrapes, so flapping shows up as a rate instead of gauge wiggles.
+// Guarded by the collector mutex.
+type statusTransitions struct {
+	level   string
+	counts  map[transition]uint64
+	changed time.Time
+
+	totalDesc   *prometheus.Desc
+	changedDesc *prometheus.Desc
+}
+
+func newStatusTransitions() statusTransitions {
+	return statusTransitions{
+		counts: make(map[transition]uint64),
+		totalDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "transitions_total"),
+			"Changes of the overall status level between scrapes",
+			[]string{"from", "to"}, nil,
+		),
+		changedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "status", "last_change_timestamp_seconds"),
+			"Unix timestamp of the last overall status change (0 if none)",
+			nil, nil,
+		),
+	}
+}
+
+func (t *statusTransitions) describe(ch chan<- *prometheus.Desc) {
+	ch <- t.totalDesc
+	ch <- t.changedDesc
+}
+
+// observe records the overall level of a successful scrape
+func (t *statusTransitions) observe(url, level string) {
+	if t.level != "" && level != t.level {
+		t.counts[transition{from: t.level, to: level}]++
+		t.changed = time.Now()
+		log.WithFields(log.Fields{"kibana_url": url, "from": t.level, "to": level}).Info("Kibana overall status changed")
+	}
+	t.level = level
+}
+
+func (t *statusTransitions) export(ch chan<- prometheus.Metric) {
+	for tr, count := range t.counts {
+		ch <- prometheus.MustNewConstMetric(t.totalDesc, prometheus.CounterValue, float64(count), tr.from, tr.to)
+	}
+	ch <- prometheus.MustNewConstMetric(t.changedDesc, prometheus.GaugeValue, unixSeconds(t.changed))
+}
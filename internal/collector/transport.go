@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TransportConfig tunes connection handling for requests to Kibana.
+// Zero values keep the net/http defaults.
+type TransportConfig struct {
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	IdleConnTimeout       time.Duration
+	KeepAlive             time.Duration
+	DisableKeepAlives     bool
+	DisableHTTP2          bool
+	DisableCompression    bool
+	// DNSCacheTTL caches Kibana host lookups for this long and closes idle
+	// connections when the addresses change (0 resolves on every new connection)
+	DNSCacheTTL time.Duration
+	// IPProtocol restricts connections to "ip4" or "ip6" ("any" or empty allows both)
+	IPProtocol string
+	// IPFallback retries with the other IP family when IPProtocol fails
+	IPFallback bool
+	// Proxy is a proxy URL, "env" for HTTP_PROXY/HTTPS_PROXY/NO_PROXY, or
+	// empty or "none" to connect directly
+	Proxy string
+	// RecordDir saves every raw Kibana response below this directory
+	RecordDir string
+	// RecordLimit is the number of recordings kept per API path (100 if zero)
+	RecordLimit int
+	// ReplayDir answers requests from recordings instead of contacting Kibana
+	ReplayDir string
+	// Chaos injects latency and failures into replayed responses
+	Chaos ChaosConfig
+	// Debug logs a redacted summary of every request
+	Debug DebugHTTPConfig
+}
+
+func newTransport(config Config) (*http.Transport, *dnsResolver) {
+	dialer := &net.Dialer{
+		Timeout:   orDefault(config.Transport.DialTimeout, 30*time.Second),
+		KeepAlive: config.Transport.KeepAlive,
+	}
+	dial := dialFunc(dialer.DialContext)
+
+	var resolver *dnsResolver
+	if config.Transport.DNSCacheTTL > 0 {
+		resolver = newDNSResolver(config.Transport.DNSCacheTTL)
+		dial = resolver.wrap(dial)
+	}
+	dial = preferIPFamily(dial, config.Transport.IPProtocol, config.Transport.IPFallback)
+
+	transport := &http.Transport{
+		DialContext: dial,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		},
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   http.DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   orDefault(config.Transport.TLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: config.Transport.ResponseHeaderTimeout,
+		DisableKeepAlives:     config.Transport.DisableKeepAlives,
+		// A custom TLS config disables HTTP/2 unless it is explicitly requested
+		ForceAttemptHTTP2: !config.Transport.DisableHTTP2,
+		// Compression is negotiated by the collector so that payload sizes can be measured
+		DisableCompression: true,
+	}
+	if config.Transport.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.Transport.MaxIdleConns
+	}
+	if config.Transport.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.Transport.MaxIdleConnsPerHost
+	}
+	if config.Transport.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.Transport.IdleConnTimeout
+	}
+	if resolver != nil {
+		resolver.onChange = transport.CloseIdleConnections
+	}
+	// Validated by ValidateProxy at startup
+	transport.Proxy, _ = proxyFunc(config.Transport.Proxy)
+	return transport, resolver
+}
+
+// ValidateProxy checks a proxy setting accepted by TransportConfig.Proxy
+func ValidateProxy(proxy string) error {
+	_, err := proxyFunc(proxy)
+	return err
+}
+
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxy {
+	case "", "none":
+		return nil, nil
+	case "env":
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, expected a URL, env or none", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q, unsupported scheme %q", proxy, u.Scheme)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// preferIPFamily dials with the network of the preferred IP family, optionally
+// falling back to the other family
+func preferIPFamily(dial dialFunc, protocol string, fallback bool) dialFunc {
+	var preferred, other string
+	switch protocol {
+	case "ip4":
+		preferred, other = "tcp4", "tcp6"
+	case "ip6":
+		preferred, other = "tcp6", "tcp4"
+	default:
+		return dial
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, preferred, addr)
+		if err == nil || !fallback || ctx.Err() != nil {
+			return conn, err
+		}
+		return dial(ctx, other, addr)
+	}
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -1,12 +1,14 @@
 package collector
 
+import "encoding/json"
+
 // KibanaStatus represents the response from /api/status
 type KibanaStatus struct {
-	Name    string        `json:"name"`
-	UUID    string        `json:"uuid"`
-	Version VersionInfo   `json:"version"`
-	Status  StatusInfo    `json:"status"`
-	Metrics MetricsInfo   `json:"metrics"`
+	Name    string      `json:"name"`
+	UUID    string      `json:"uuid"`
+	Version VersionInfo `json:"version"`
+	Status  StatusInfo  `json:"status"`
+	Metrics MetricsInfo `json:"metrics"`
 }
 
 // VersionInfo contains version details
@@ -19,31 +21,46 @@ type VersionInfo struct {
 
 // StatusInfo contains overall and service status
 type StatusInfo struct {
-	Overall  OverallStatus           `json:"overall"`
-	Core     map[string]*ServiceStatus `json:"core"`
-	Plugins  map[string]*ServiceStatus `json:"plugins"`
+	Overall OverallStatus             `json:"overall"`
+	Core    map[string]*ServiceStatus `json:"core"`
+	Plugins map[string]*ServiceStatus `json:"plugins"`
+	// Statuses is the legacy (Kibana 7, OpenSearch Dashboards) list of service statuses
+	Statuses []LegacyServiceStatus `json:"statuses"`
 }
 
 // OverallStatus represents the overall system status
 type OverallStatus struct {
 	Level   string `json:"level"`
 	Summary string `json:"summary"`
+	// State is the legacy green/yellow/red status
+	State string `json:"state"`
 }
 
 // ServiceStatus represents individual service status
 type ServiceStatus struct {
 	Level   string `json:"level"`
 	Summary string `json:"summary"`
+	// Detail and Meta are reported by some services, e.g. savedObjects
+	Detail string          `json:"detail,omitempty"`
+	Meta   json.RawMessage `json:"meta,omitempty"`
+}
+
+// LegacyServiceStatus is a service status in the legacy format
+type LegacyServiceStatus struct {
+	ID      string `json:"id"`
+	State   string `json:"state"`
+	Message string `json:"message"`
 }
 
 // MetricsInfo contains all metrics data
 type MetricsInfo struct {
-	CollectedAt           string                 `json:"collected_at"`
-	ConcurrentConnections *int64                 `json:"concurrent_connections"`
-	Process               ProcessMetrics         `json:"process"`
-	OS                    *OSMetrics             `json:"os"`
-	Requests              *RequestMetrics        `json:"requests"`
-	ResponseTimes         *ResponseTimeMetrics   `json:"response_times"`
+	CollectedAt           string               `json:"collected_at"`
+	CollectionInterval    *float64             `json:"collection_interval_in_millis"`
+	ConcurrentConnections *int64               `json:"concurrent_connections"`
+	Process               ProcessMetrics       `json:"process"`
+	OS                    *OSMetrics           `json:"os"`
+	Requests              *RequestMetrics      `json:"requests"`
+	ResponseTimes         *ResponseTimeMetrics `json:"response_times"`
 }
 
 // ProcessMetrics contains process-level metrics
@@ -68,9 +85,9 @@ type HeapMetrics struct {
 
 // OSMetrics contains operating system metrics
 type OSMetrics struct {
-	CPU    *CPUMetrics       `json:"cpu"`
-	Load   *LoadMetrics      `json:"load"`
-	Memory *OSMemoryMetrics  `json:"memory"`
+	CPU    *CPUMetrics      `json:"cpu"`
+	Load   *LoadMetrics     `json:"load"`
+	Memory *OSMemoryMetrics `json:"memory"`
 }
 
 // CPUMetrics contains CPU usage details
@@ -108,4 +125,7 @@ type RequestMetrics struct {
 type ResponseTimeMetrics struct {
 	Avg *float64 `json:"avg_in_millis"`
 	Max *float64 `json:"max_in_millis"`
+	// P95 and P99 are only reported by some Kibana builds
+	P95 *float64 `json:"p95_in_millis"`
+	P99 *float64 `json:"p99_in_millis"`
 }
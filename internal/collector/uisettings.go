@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const uiSettingsEndpoint = "/api/kibana/settings"
+
+// uiSettingsResponse is the relevant part of /api/kibana/settings, which
+// lists the advanced settings changed from their defaults
+type uiSettingsResponse struct {
+	Settings map[string]struct {
+		UserValue json.RawMessage `json:"userValue"`
+	} `json:"settings"`
+}
+
+type uiSettingsDescs struct {
+	overridden *prometheus.Desc
+	hash       *prometheus.Desc
+	enabled    *prometheus.Desc
+}
+
+func newUISettingsDescs() uiSettingsDescs {
+	return uiSettingsDescs{
+		overridden: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ui_setting", "overridden"),
+			"Whether an advanced setting is changed from its default",
+			[]string{"key"}, nil,
+		),
+		hash: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ui_setting", "value_hash"),
+			"FNV-1a hash of an advanced setting's JSON value (0 while at its default)",
+			[]string{"key"}, nil,
+		),
+		enabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ui_setting", "enabled"),
+			"Value of a boolean advanced setting that is changed from its default",
+			[]string{"key"}, nil,
+		),
+	}
+}
+
+func (d uiSettingsDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.overridden
+	ch <- d.hash
+	ch <- d.enabled
+}
+
+// collectUISettings exports the configured advanced settings of the default
+// space. Values are only exported as hashes, and booleans as such, so that
+// settings holding URLs or other details do not leak into Prometheus.
+func (c *KibanaCollector) collectUISettings(ctx context.Context, ch chan<- prometheus.Metric) {
+	if c.forbidden.skip(CollectorUISettings, uiSettingsEndpoint) {
+		return
+	}
+	var response uiSettingsResponse
+	if err := c.getJSON(ctx, uiSettingsEndpoint, &response); err != nil {
+		if !c.forbidden.observe(c.config.KibanaURL, CollectorUISettings, uiSettingsEndpoint, err) {
+			log.WithError(err).WithFields(log.Fields{
+				"target":     c.config.KibanaURL,
+				"error_code": ErrorCode(err),
+			}).Warn("Failed to scrape advanced settings")
+		}
+		return
+	}
+
+	for _, key := range c.config.UISettings {
+		setting, ok := response.Settings[key]
+		if !ok || len(setting.UserValue) == 0 || string(setting.UserValue) == "null" {
+			ch <- prometheus.MustNewConstMetric(c.uiSettings.overridden, prometheus.GaugeValue, 0, key)
+			ch <- prometheus.MustNewConstMetric(c.uiSettings.hash, prometheus.GaugeValue, 0, key)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.uiSettings.overridden, prometheus.GaugeValue, 1, key)
+		ch <- prometheus.MustNewConstMetric(c.uiSettings.hash, prometheus.GaugeValue, float64(hashValue(setting.UserValue)), key)
+		var enabled bool
+		if json.Unmarshal(setting.UserValue, &enabled) == nil {
+			value := 0.0
+			if enabled {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.uiSettings.enabled, prometheus.GaugeValue, value, key)
+		}
+	}
+}
+
+// hashValue hashes a JSON value independently of its formatting
+func hashValue(raw json.RawMessage) uint32 {
+	var value interface{}
+	if json.Unmarshal(raw, &value) == nil {
+		if compact, err := json.Marshal(value); err == nil {
+			raw = compact
+		}
+	}
+	h := fnv.New32a()
+	h.Write(raw)
+	return h.Sum32()
+}
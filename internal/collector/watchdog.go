@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchdog cancels scrapes that hold the collector mutex for longer than
+// Config.WatchdogTimeout, e.g. on a connection stuck without a deadline, so
+// a single hung request cannot block every later scrape of the target
+type watchdog struct {
+	timeout time.Duration
+	trips   atomic.Uint64
+	desc    *prometheus.Desc
+}
+
+func newWatchdog(timeout time.Duration) *watchdog {
+	return &watchdog{
+		timeout: timeout,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "watchdog_trips_total"),
+			"Scrapes cancelled by the watchdog for running longer than --watchdog-multiplier times --timeout",
+			nil, nil,
+		),
+	}
+}
+
+// watch returns a context that is cancelled once the watchdog timeout has
+// passed, which aborts the requests in flight, and a function that stops
+// watching. reset is called on timeout to drop the remaining connections.
+func (w *watchdog) watch(ctx context.Context, url string, reset func()) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(w.timeout, func() {
+		w.trips.Add(1)
+		log.WithFields(log.Fields{
+			"kibana_url": url,
+			"timeout":    w.timeout.String(),
+		}).Error("Scrape is wedged, cancelling it and resetting the connections to Kibana")
+		cancel()
+		reset()
+	})
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+func (w *watchdog) export(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(w.desc, prometheus.CounterValue, float64(w.trips.Load()))
+}
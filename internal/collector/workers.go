@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WorkerPools bound how many targets run each collector at once. Every
+// collector with workers configured has its own pool and queue, so a slow
+// collector holds on to its own workers only and cannot starve the status
+// scrapes of a large fleet. Collectors without a pool run unbounded.
+type WorkerPools struct {
+	pools map[string]*workerPool
+
+	workersDesc  *prometheus.Desc
+	busyDesc     *prometheus.Desc
+	queuedDesc   *prometheus.Desc
+	rejectedDesc *prometheus.Desc
+}
+
+type workerPool struct {
+	slots    chan struct{}
+	queue    int
+	mutex    sync.Mutex
+	queued   int
+	rejected uint64
+}
+
+// errQueueFull is returned when a collector's queue has no room for a run
+var errQueueFull = errors.New("collector queue is full")
+
+// NewWorkerPools creates a pool for every collector with workers in its
+// settings, or returns nil if there are none
+func NewWorkerPools(settings map[string]CollectorSettings) *WorkerPools {
+	pools := make(map[string]*workerPool)
+	for name, s := range settings {
+		if s.Workers > 0 {
+			pools[name] = &workerPool{slots: make(chan struct{}, s.Workers), queue: s.Queue}
+		}
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+	return &WorkerPools{
+		pools: pools,
+		workersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_workers"),
+			"Targets a collector may run for at once",
+			[]string{"collector"}, nil,
+		),
+		busyDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_workers_busy"),
+			"Targets a collector is running for",
+			[]string{"collector"}, nil,
+		),
+		queuedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_queue_depth"),
+			"Targets waiting for a worker of a collector",
+			[]string{"collector"}, nil,
+		),
+		rejectedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_queue_rejected_total"),
+			"Collector runs skipped because the collector's queue was full",
+			[]string{"collector"}, nil,
+		),
+	}
+}
+
+// acquire waits for a worker of the named collector and returns the function
+// releasing it. It fails when the queue is full or ctx ends first.
+func (p *WorkerPools) acquire(ctx context.Context, name string) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	pool, ok := p.pools[name]
+	if !ok {
+		return func() {}, nil
+	}
+	release := func() { <-pool.slots }
+	select {
+	case pool.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	pool.mutex.Lock()
+	if pool.queue > 0 && pool.queued >= pool.queue {
+		pool.rejected++
+		pool.mutex.Unlock()
+		return nil, errQueueFull
+	}
+	pool.queued++
+	pool.mutex.Unlock()
+	defer func() {
+		pool.mutex.Lock()
+		pool.queued--
+		pool.mutex.Unlock()
+	}()
+
+	select {
+	case pool.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a %s worker: %w", name, ctx.Err())
+	}
+}
+
+// Describe implements prometheus.Collector
+func (p *WorkerPools) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.workersDesc
+	ch <- p.busyDesc
+	ch <- p.queuedDesc
+	ch <- p.rejectedDesc
+}
+
+// Collect implements prometheus.Collector
+func (p *WorkerPools) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range slices.Sorted(maps.Keys(p.pools)) {
+		pool := p.pools[name]
+		pool.mutex.Lock()
+		queued, rejected := pool.queued, pool.rejected
+		pool.mutex.Unlock()
+		ch <- prometheus.MustNewConstMetric(p.workersDesc, prometheus.GaugeValue, float64(cap(pool.slots)), name)
+		ch <- prometheus.MustNewConstMetric(p.busyDesc, prometheus.GaugeValue, float64(len(pool.slots)), name)
+		ch <- prometheus.MustNewConstMetric(p.queuedDesc, prometheus.GaugeValue, float64(queued), name)
+		ch <- prometheus.MustNewConstMetric(p.rejectedDesc, prometheus.CounterValue, float64(rejected), name)
+	}
+}
@@ -0,0 +1,161 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrNotFound is returned when the API server responds with 404
+var ErrNotFound = errors.New("not found")
+
+// Client is a minimal Kubernetes API client using the pod's service account
+type Client struct {
+	host string
+	// tokenFile is read for every request, as projected service account
+	// tokens are rotated by the kubelet and expire
+	tokenFile string
+	client    *http.Client
+}
+
+// NewInClusterClient creates a client from the service account mounted into the pod
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running inside a Kubernetes cluster")
+	}
+
+	tokenFile := serviceAccountDir + "/token"
+	if _, err := readToken(tokenFile); err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in service account CA")
+	}
+
+	return &Client{
+		host:      "https://" + net.JoinHostPort(host, port),
+		tokenFile: tokenFile,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+	}, nil
+}
+
+// readToken reads the service account token from file
+func readToken(file string) (string, error) {
+	token, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading service account token: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// authorize sets the current service account token on req
+func (c *Client) authorize(req *http.Request) error {
+	token, err := readToken(c.tokenFile)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Stream issues a GET for a long-running watch and passes the response body to read
+func (c *Client) Stream(ctx context.Context, path string, read func(*json.Decoder) error) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+path, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// Watches are long-lived, so bypass the client's overall timeout
+	client := *c.client
+	client.Timeout = 0
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kubernetes API returned status %d: %s", resp.StatusCode, string(msg))
+	}
+
+	err = read(json.NewDecoder(resp.Body))
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// Do sends a request to the API server, encoding in as the body and decoding the response into out
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kubernetes API returned status %d: %s", resp.StatusCode, string(msg))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
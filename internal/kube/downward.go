@@ -0,0 +1,43 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// downwardSources maps a label name to the environment variable and
+// downward API volume file it can be read from. The labels describe the
+// exporter's pod, so they are prefixed to not clash with target labels
+// or the pod and namespace labels Prometheus adds from service discovery.
+var downwardSources = []struct {
+	label string
+	env   string
+	file  string
+}{
+	{"exporter_pod", "POD_NAME", "pod_name"},
+	{"exporter_namespace", "POD_NAMESPACE", "namespace"},
+	{"exporter_node", "NODE_NAME", "node_name"},
+}
+
+// DownwardLabels returns exporter_pod, exporter_namespace and exporter_node
+// labels exposed through the downward API. Environment variables take
+// precedence over files in dir; labels that are not available are omitted.
+func DownwardLabels(dir string) map[string]string {
+	labels := make(map[string]string)
+	for _, src := range downwardSources {
+		if v := os.Getenv(src.env); v != "" {
+			labels[src.label] = v
+			continue
+		}
+		if dir == "" {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, src.file)); err == nil {
+			if v := strings.TrimSpace(string(data)); v != "" {
+				labels[src.label] = v
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Endpoint is a single ready pod backing a Service
+type Endpoint struct {
+	IP       string
+	Port     int
+	PodName  string
+	NodeName string
+}
+
+type endpoints struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Subsets []struct {
+		Addresses []struct {
+			IP        string  `json:"ip"`
+			NodeName  *string `json:"nodeName"`
+			TargetRef *struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"targetRef"`
+		} `json:"addresses"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+type endpointsEvent struct {
+	Type   string    `json:"type"`
+	Object endpoints `json:"object"`
+}
+
+// EndpointsWatcher follows the Endpoints of a Service and reports its ready pods
+type EndpointsWatcher struct {
+	Client    *Client
+	Namespace string
+	Service   string
+	// Port selects the Service port by name; empty uses the first port
+	Port string
+}
+
+// Run lists and watches the Endpoints until ctx is cancelled, calling onChange
+// with the full set of ready endpoints whenever it changes
+func (w *EndpointsWatcher) Run(ctx context.Context, onChange func([]Endpoint)) {
+	backoff := time.Second
+	for {
+		err := w.watch(ctx, onChange)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.WithError(err).WithField("service", w.Namespace+"/"+w.Service).Warn("Endpoints watch failed")
+			backoff *= 2
+			if backoff > time.Minute {
+				backoff = time.Minute
+			}
+		} else {
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (w *EndpointsWatcher) watch(ctx context.Context, onChange func([]Endpoint)) error {
+	var current endpoints
+	path := fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s", w.Namespace, w.Service)
+	if err := w.Client.Do(ctx, "GET", path, nil, &current); err != nil && err != ErrNotFound {
+		return fmt.Errorf("getting endpoints: %w", err)
+	}
+	onChange(w.ready(current))
+
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("fieldSelector", "metadata.name="+w.Service)
+	query.Set("timeoutSeconds", "300")
+	if current.Metadata.ResourceVersion != "" {
+		query.Set("resourceVersion", current.Metadata.ResourceVersion)
+	}
+	watchPath := fmt.Sprintf("/api/v1/namespaces/%s/endpoints?%s", w.Namespace, query.Encode())
+
+	return w.Client.Stream(ctx, watchPath, func(dec *json.Decoder) error {
+		for {
+			var event endpointsEvent
+			if err := dec.Decode(&event); err != nil {
+				return err
+			}
+			switch event.Type {
+			case "ADDED", "MODIFIED":
+				onChange(w.ready(event.Object))
+			case "DELETED":
+				onChange(nil)
+			case "ERROR":
+				return fmt.Errorf("watch returned an error event")
+			}
+		}
+	})
+}
+
+func (w *EndpointsWatcher) ready(ep endpoints) []Endpoint {
+	var result []Endpoint
+	for _, subset := range ep.Subsets {
+		port := 0
+		for _, p := range subset.Ports {
+			if w.Port == "" || p.Name == w.Port {
+				port = p.Port
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		for _, addr := range subset.Addresses {
+			endpoint := Endpoint{IP: addr.IP, Port: port}
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				endpoint.PodName = addr.TargetRef.Name
+			}
+			if addr.NodeName != nil {
+				endpoint.NodeName = *addr.NodeName
+			}
+			result = append(result, endpoint)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].IP < result[j].IP })
+	return result
+}
@@ -0,0 +1,119 @@
+// Package notify sends Kibana state changes to external receivers.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gnanirahulnutakki/kibana-prometheus-exporter/internal/collector"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Payload is the JSON body posted to the webhook
+type Payload struct {
+	Target string    `json:"target"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Webhook posts state changes to a URL. A change is only sent once it has
+// lasted for the debounce period, so a target flapping back is not reported.
+type Webhook struct {
+	url      string
+	debounce time.Duration
+	client   *http.Client
+
+	mutex   sync.Mutex
+	pending map[string]*pendingChange
+}
+
+type pendingChange struct {
+	change collector.StateChange
+	timer  *time.Timer
+}
+
+// NewWebhook creates a webhook notifier
+func NewWebhook(url string, debounce time.Duration) *Webhook {
+	return &Webhook{
+		url:      url,
+		debounce: debounce,
+		client:   &http.Client{Timeout: defaultTimeout},
+		pending:  make(map[string]*pendingChange),
+	}
+}
+
+// Notify queues a state change; it does not block
+func (w *Webhook) Notify(change collector.StateChange) {
+	if w.debounce <= 0 {
+		go w.send(change)
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if p, ok := w.pending[change.URL]; ok {
+		// Keep the state from before the first unsent change
+		change.From = p.change.From
+		p.timer.Stop()
+		delete(w.pending, change.URL)
+		if change.From == change.To {
+			log.WithField("target", change.URL).Debug("State change reverted within the debounce period, not notifying")
+			return
+		}
+	}
+	p := &pendingChange{change: change}
+	p.timer = time.AfterFunc(w.debounce, func() {
+		w.mutex.Lock()
+		if w.pending[change.URL] != p {
+			w.mutex.Unlock()
+			return
+		}
+		delete(w.pending, change.URL)
+		w.mutex.Unlock()
+		w.send(p.change)
+	})
+	w.pending[change.URL] = p
+}
+
+func (w *Webhook) send(change collector.StateChange) {
+	logger := log.WithFields(log.Fields{"target": change.URL, "from": change.From, "to": change.To})
+	if err := w.post(Payload{Target: change.URL, From: change.From, To: change.To, Time: change.Time, Error: change.Error}); err != nil {
+		logger.WithError(err).Warn("Failed to send webhook notification")
+		return
+	}
+	logger.Debug("Sent webhook notification")
+}
+
+func (w *Webhook) post(payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package plugin runs external helper commands and merges the metrics they
+// print into the exporter's output.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// Output formats of a plugin
+const (
+	FormatJSON       = "json"
+	FormatPrometheus = "prometheus"
+)
+
+// Config describes an external command whose output is exported as metrics
+type Config struct {
+	// Name namespaces the plugin's metrics as kibana_plugin_<name>_<metric>
+	Name string `json:"name"`
+	// Command is the executable and its arguments
+	Command []string `json:"command"`
+	// Format is json (default) or prometheus (text exposition format)
+	Format string `json:"format"`
+	// Timeout kills the command after this long, e.g. "10s" (default 10s)
+	Timeout string `json:"timeout"`
+}
+
+// jsonOutput is what a json plugin prints on stdout
+type jsonOutput struct {
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+type jsonMetric struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help"`
+	Type   string            `json:"type"`
+	Value  float64           `json:"value"`
+	Labels map[string]string `json:"labels"`
+}
+
+type pluginsFile struct {
+	Plugins []Config `json:"plugins"`
+}
+
+var nameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Load reads and validates a JSON plugins file
+func Load(file string) ([]Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config pluginsFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+
+	names := make(map[string]bool)
+	for _, p := range config.Plugins {
+		if !nameRE.MatchString(p.Name) {
+			return nil, fmt.Errorf("%s: invalid plugin name %q", file, p.Name)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("%s: plugin %q is defined more than once", file, p.Name)
+		}
+		names[p.Name] = true
+		if len(p.Command) == 0 {
+			return nil, fmt.Errorf("%s: plugin %q has no command", file, p.Name)
+		}
+		switch p.Format {
+		case "", FormatJSON, FormatPrometheus:
+		default:
+			return nil, fmt.Errorf("%s: plugin %q: unknown format %q", file, p.Name, p.Format)
+		}
+		if _, err := parseTimeout(p.Timeout); err != nil {
+			return nil, fmt.Errorf("%s: plugin %q: %w", file, p.Name, err)
+		}
+	}
+	return config.Plugins, nil
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return 10 * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", s)
+	}
+	return d, nil
+}
+
+// Collector runs every plugin on each scrape. Plugins run concurrently and
+// their metrics are not known in advance, so the collector is unchecked.
+type Collector struct {
+	plugins []Config
+
+	success  *prometheus.Desc
+	duration *prometheus.Desc
+}
+
+// NewCollector creates a collector for plugins validated by Load
+func NewCollector(plugins []Config) *Collector {
+	return &Collector{
+		plugins: plugins,
+		success: prometheus.NewDesc(
+			"kibana_exporter_plugin_success",
+			"Whether the last run of a plugin succeeded",
+			[]string{"plugin"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			"kibana_exporter_plugin_duration_seconds",
+			"Duration of the last run of a plugin",
+			[]string{"plugin"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector. It sends nothing, which registers
+// the collector as unchecked.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, p := range c.plugins {
+		wg.Add(1)
+		go func(p Config) {
+			defer wg.Done()
+			c.collectPlugin(p, ch)
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (c *Collector) collectPlugin(p Config, ch chan<- prometheus.Metric) {
+	start := time.Now()
+	metrics, err := run(p)
+	ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, time.Since(start).Seconds(), p.Name)
+
+	if err != nil {
+		log.WithError(err).WithField("plugin", p.Name).Warn("Plugin failed")
+		ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, 0, p.Name)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, 1, p.Name)
+	for _, m := range metrics {
+		ch <- m
+	}
+}
+
+// run executes the plugin and converts its output to metrics
+func run(p Config) ([]prometheus.Metric, error) {
+	timeout, _ := parseTimeout(p.Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait for grandchildren holding stdout open after the command is killed
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	prefix := "kibana_plugin_" + p.Name + "_"
+	if p.Format == FormatPrometheus {
+		return parseExposition(&stdout, prefix)
+	}
+	return parseJSON(stdout.Bytes(), prefix)
+}
+
+func parseJSON(data []byte, prefix string) ([]prometheus.Metric, error) {
+	var out jsonOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing output: %w", err)
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(out.Metrics))
+	for _, m := range out.Metrics {
+		valueType := prometheus.GaugeValue
+		switch m.Type {
+		case "", "gauge":
+		case "counter":
+			valueType = prometheus.CounterValue
+		default:
+			return nil, fmt.Errorf("metric %q: unknown type %q", m.Name, m.Type)
+		}
+
+		names := make([]string, 0, len(m.Labels))
+		for name := range m.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = m.Labels[name]
+		}
+
+		help := m.Help
+		if help == "" {
+			help = "Plugin metric " + m.Name
+		}
+		metric, err := prometheus.NewConstMetric(prometheus.NewDesc(prefix+m.Name, help, names, nil), valueType, m.Value, values...)
+		if err != nil {
+			return nil, fmt.Errorf("metric %q: %w", m.Name, err)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func parseExposition(r *bytes.Buffer, prefix string) ([]prometheus.Metric, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output: %w", err)
+	}
+
+	var metrics []prometheus.Metric
+	for name, family := range families {
+		for _, m := range family.GetMetric() {
+			metric, err := convert(prefix+name, family, m)
+			if err != nil {
+				return nil, fmt.Errorf("metric %q: %w", name, err)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, nil
+}
+
+// convert turns a parsed sample into a const metric of the same type
+func convert(name string, family *dto.MetricFamily, m *dto.Metric) (prometheus.Metric, error) {
+	names := make([]string, 0, len(m.GetLabel()))
+	values := make([]string, 0, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		names = append(names, label.GetName())
+		values = append(values, label.GetValue())
+	}
+	help := family.GetHelp()
+	if help == "" {
+		help = "Plugin metric " + family.GetName()
+	}
+	desc := prometheus.NewDesc(name, help, names, nil)
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		return prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), values...)
+	case dto.MetricType_GAUGE:
+		return prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), values...)
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		buckets := make(map[float64]uint64, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		return prometheus.NewConstHistogram(desc, h.GetSampleCount(), h.GetSampleSum(), buckets, values...)
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		quantiles := make(map[float64]float64, len(s.GetQuantile()))
+		for _, q := range s.GetQuantile() {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+		return prometheus.NewConstSummary(desc, s.GetSampleCount(), s.GetSampleSum(), quantiles, values...)
+	default:
+		return prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), values...)
+	}
+}
@@ -0,0 +1,377 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	scopeName      = "github.com/gnanirahulnutakki/kibana-prometheus-exporter"
+	maxQueueSize   = 2048
+	maxBatchSize   = 512
+	defaultFlush   = 5 * time.Second
+	defaultTimeout = 10 * time.Second
+)
+
+// Span kinds as defined by the OTLP protobuf schema
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// Config holds the tracing configuration
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces URL, e.g. http://otel-collector:4318/v1/traces
+	Endpoint      string
+	ServiceName   string
+	Headers       map[string]string
+	SampleRatio   float64
+	FlushInterval time.Duration
+	Timeout       time.Duration
+}
+
+// Tracer records spans and exports them in batches via OTLP/HTTP (JSON encoding).
+// A nil *Tracer is valid and records nothing.
+type Tracer struct {
+	config Config
+	client *http.Client
+
+	mutex sync.Mutex
+	queue []*Span
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Span is a single timed operation. A nil *Span is valid and records nothing.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+
+	mutex      sync.Mutex
+	attributes map[string]interface{}
+	events     []spanEvent
+	errMessage string
+	ended      bool
+}
+
+type spanEvent struct {
+	name       string
+	time       time.Time
+	attributes map[string]interface{}
+}
+
+type spanContextKey struct{}
+
+// unsampledKey marks a context whose root span was dropped by sampling,
+// so that child spans are dropped too
+type unsampledKey struct{}
+
+// New creates a tracer and starts its background exporter.
+// It returns nil when no endpoint is configured.
+func New(config Config) *Tracer {
+	if config.Endpoint == "" {
+		return nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "kibana-prometheus-exporter"
+	}
+	if config.SampleRatio <= 0 || config.SampleRatio > 1 {
+		config.SampleRatio = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlush
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	t := &Tracer{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start begins a new span. If ctx carries a span, the new span becomes its child.
+func (t *Tracer) Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if t == nil || ctx.Value(unsampledKey{}) != nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+		if !sampled(span.traceID, t.config.SampleRatio) {
+			return context.WithValue(ctx, unsampledKey{}, true), nil
+		}
+	}
+	_, _ = rand.Read(span.spanID[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Shutdown flushes pending spans and stops the background exporter
+func (t *Tracer) Shutdown(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+}
+
+// SpanFromContext returns the span stored in ctx, if any
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SetAttribute records a key/value pair on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.attributes[key] = value
+	s.mutex.Unlock()
+}
+
+// AddEvent records a timestamped event on the span
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.events = append(s.events, spanEvent{name: name, time: time.Now(), attributes: attributes})
+	s.mutex.Unlock()
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.errMessage = err.Error()
+	s.mutex.Unlock()
+}
+
+// TraceParent returns the W3C traceparent header value for the span
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+
+	s.tracer.enqueue(s)
+}
+
+func (t *Tracer) enqueue(span *Span) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.queue) >= maxQueueSize {
+		log.Debug("Trace queue full, dropping span")
+		return
+	}
+	t.queue = append(t.queue, span)
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.flush()
+		case <-t.stop:
+			t.flush()
+			return
+		}
+	}
+}
+
+func (t *Tracer) flush() {
+	for {
+		t.mutex.Lock()
+		n := len(t.queue)
+		if n == 0 {
+			t.mutex.Unlock()
+			return
+		}
+		if n > maxBatchSize {
+			n = maxBatchSize
+		}
+		batch := t.queue[:n:n]
+		t.queue = t.queue[n:]
+		t.mutex.Unlock()
+
+		if err := t.export(batch); err != nil {
+			log.WithError(err).Warn("Failed to export traces")
+			return
+		}
+	}
+}
+
+func (t *Tracer) export(spans []*Span) error {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return fmt.Errorf("encoding spans: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// encode converts spans into the OTLP/JSON ExportTraceServiceRequest shape
+func (t *Tracer) encode(spans []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		s.mutex.Lock()
+		span := map[string]interface{}{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        encodeAttributes(s.attributes),
+		}
+		if s.parentID != ([8]byte{}) {
+			span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if len(s.events) > 0 {
+			events := make([]map[string]interface{}, 0, len(s.events))
+			for _, e := range s.events {
+				events = append(events, map[string]interface{}{
+					"name":         e.name,
+					"timeUnixNano": strconv.FormatInt(e.time.UnixNano(), 10),
+					"attributes":   encodeAttributes(e.attributes),
+				})
+			}
+			span["events"] = events
+		}
+		if s.errMessage != "" {
+			span["status"] = map[string]interface{}{"code": 2, "message": s.errMessage}
+		}
+		s.mutex.Unlock()
+		encoded = append(encoded, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": encodeAttributes(map[string]interface{}{
+					"service.name": t.config.ServiceName,
+				}),
+			},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": scopeName},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+func encodeAttributes(attributes map[string]interface{}) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attributes))
+	for k, v := range attributes {
+		var value map[string]interface{}
+		switch val := v.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": val}
+		case bool:
+			value = map[string]interface{}{"boolValue": val}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(val)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": val}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": k, "value": value})
+	}
+	return encoded
+}
+
+// sampled makes a deterministic decision from the trace ID so that
+// the same trace is sampled consistently
+func sampled(traceID [16]byte, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	var v uint64
+	for _, b := range traceID[8:] {
+		v = v<<8 | uint64(b)
+	}
+	return float64(v>>11)/float64(1<<53) < ratio
+}
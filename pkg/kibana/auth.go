@@ -0,0 +1,166 @@
+// Package kibana holds the extension points of the exporter's Kibana client
+// for programs that build their own exporter binary.
+package kibana
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AuthProvider authenticates requests to Kibana. Apply must keep adding the
+// same credentials until Refresh is called: the exporter prepares its status
+// request once and only applies the credentials again after a refresh.
+// Implementations must be safe for concurrent use.
+type AuthProvider interface {
+	// Apply adds credentials to req
+	Apply(req *http.Request) error
+	// Refresh renews the credentials. It is called when Kibana answers 401.
+	Refresh() error
+}
+
+// AuthConfig is passed to an AuthProviderFactory for each Kibana target
+type AuthConfig struct {
+	// Username and Password are the target's credentials, if any
+	Username string
+	Password string
+	// Params are provider-specific settings (--auth-provider-param)
+	Params map[string]string
+}
+
+// AuthProviderFactory creates the AuthProvider of one Kibana target
+type AuthProviderFactory func(config AuthConfig) (AuthProvider, error)
+
+// Built-in auth providers
+const (
+	// AuthBasic sends the username and password with HTTP basic
+	// authentication, or nothing without a username
+	AuthBasic = "basic"
+	// AuthNone sends no credentials
+	AuthNone = "none"
+	// AuthAPIKey sends the api_key param as an Elasticsearch API key, either
+	// encoded as Kibana shows it or as id:key
+	AuthAPIKey = "apikey"
+	// AuthBearer sends the token param as a bearer token, e.g. a JWT for an
+	// OIDC proxy in front of Kibana
+	AuthBearer = "bearer"
+)
+
+var (
+	mutex     sync.RWMutex
+	factories = make(map[string]AuthProviderFactory)
+)
+
+func init() {
+	RegisterAuthProvider(AuthBasic, func(config AuthConfig) (AuthProvider, error) {
+		return basicAuth{username: config.Username, password: config.Password}, nil
+	})
+	RegisterAuthProvider(AuthNone, func(AuthConfig) (AuthProvider, error) {
+		return noAuth{}, nil
+	})
+	RegisterAuthProvider(AuthAPIKey, func(config AuthConfig) (AuthProvider, error) {
+		key := config.Params["api_key"]
+		if key == "" {
+			return nil, errors.New("api_key param is required")
+		}
+		if strings.Contains(key, ":") {
+			key = base64.StdEncoding.EncodeToString([]byte(key))
+		}
+		return apiKeyAuth{header: "ApiKey " + key}, nil
+	})
+	RegisterAuthProvider(AuthBearer, func(config AuthConfig) (AuthProvider, error) {
+		token := config.Params["token"]
+		if token == "" {
+			return nil, errors.New("token param is required")
+		}
+		return bearerAuth{header: "Bearer " + token}, nil
+	})
+}
+
+// RegisterAuthProvider makes an auth provider available under name, usually
+// from the init function of the package implementing it. It panics if name
+// is already registered.
+func RegisterAuthProvider(name string, factory AuthProviderFactory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("kibana: auth provider %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// NewAuthProvider creates the auth provider registered under name; an empty
+// name selects AuthBasic
+func NewAuthProvider(name string, config AuthConfig) (AuthProvider, error) {
+	if name == "" {
+		name = AuthBasic
+	}
+	mutex.RLock()
+	factory, ok := factories[name]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown auth provider %q, expected one of %v", name, AuthProviders())
+	}
+	provider, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("auth provider %s: %w", name, err)
+	}
+	return provider, nil
+}
+
+// AuthProviders returns the names of the registered auth providers, sorted
+func AuthProviders() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) Apply(req *http.Request) error {
+	if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	}
+	return nil
+}
+
+func (basicAuth) Refresh() error { return nil }
+
+type noAuth struct{}
+
+func (noAuth) Apply(*http.Request) error { return nil }
+
+func (noAuth) Refresh() error { return nil }
+
+type apiKeyAuth struct {
+	header string
+}
+
+func (a apiKeyAuth) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", a.header)
+	return nil
+}
+
+func (apiKeyAuth) Refresh() error { return nil }
+
+type bearerAuth struct {
+	header string
+}
+
+func (a bearerAuth) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", a.header)
+	return nil
+}
+
+func (bearerAuth) Refresh() error { return nil }